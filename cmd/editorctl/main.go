@@ -0,0 +1,97 @@
+// Client for the editor control socket.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/jmigpin/editor/core/ctlproto"
+)
+
+func main() {
+	if err := main2(); err != nil {
+		fmt.Fprintf(os.Stderr, "editorctl: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func main2() error {
+	flag.Usage = usage
+	addr := flag.String("addr", os.Getenv(ctlproto.SocketEnv), "socket address")
+	flag.Parse()
+	args := flag.Args()
+	if len(args) < 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *addr == "" {
+		return fmt.Errorf("missing socket address (%v not set)", ctlproto.SocketEnv)
+	}
+
+	req := &ctlproto.Request{Op: args[0], Path: args[1]}
+	if req.Op == "write" {
+		if len(args) > 2 {
+			req.Data = []byte(strings.Join(args[2:], " "))
+		} else {
+			b, err := ioutil.ReadAll(os.Stdin)
+			if err != nil {
+				return err
+			}
+			req.Data = b
+		}
+	} else if len(args) != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	conn, err := net.Dial("unix", *addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err := ctlproto.WriteRequest(conn, req); err != nil {
+		return err
+	}
+	br := bufio.NewReader(conn)
+	data, stream, err := ctlproto.ReadResponse(br)
+	if err != nil {
+		return err
+	}
+	if stream {
+		_, err := io.Copy(os.Stdout, br)
+		return err
+	}
+	_, err = os.Stdout.Write(data)
+	return err
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, `Usage:
+	editorctl [-addr=<socket>] read <path>
+	editorctl [-addr=<socket>] write <path> [<data>]
+Data is read from stdin if not given as argument.
+Paths:
+	index		rows list: "<id> <name>"
+	new		open a row (write a filename, returns the row id)
+	ctl		run a top toolbar cmd (write)
+	event		editor events (read, keeps streaming)
+	<id>/body	row text
+	<id>/tag	row toolbar (after the name)
+	<id>/addr	row selection: "<start> <end>"
+	<id>/ctl	row info (read), run a row toolbar cmd (write)
+	<id>/event	row events (read, keeps streaming)
+Examples:
+	editorctl read index
+	editorctl write 3/ctl Save
+	echo hello | editorctl write 3/body
+	editorctl read event
+`)
+	flag.PrintDefaults()
+}
@@ -0,0 +1,434 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jmigpin/editor/core/fsys"
+	"github.com/jmigpin/editor/ui"
+	"github.com/jmigpin/editor/util/diffutil"
+	"github.com/jmigpin/editor/util/osutil"
+)
+
+// Session name used to autosave the layout.
+const AutoSaveSessionName = "autosave"
+
+const autoSaveInterval = 10 * time.Second
+
+// Number of the most recent autosave files kept (one per editor instance).
+const autoSaveKeep = 10
+
+// Periodically saves the layout (session) and journals the unsaved files contents to a recovery directory. Also saves on exit.
+// The layout is saved to a file per editor instance, to not overwrite the sessions file (or each other) when running several instances.
+type AutoSave struct {
+	ed        *Editor
+	instance  string         // unique id of this editor instance
+	pstart    string         // process start id (if known)
+	filename  string         // autosave file of this instance
+	session   []byte         // last saved session
+	journaled map[int][]byte // recovery id -> hash of the content journaled by this process
+	stop      chan struct{}
+}
+
+func NewAutoSave(ed *Editor) *AutoSave {
+	as := &AutoSave{ed: ed, journaled: map[int][]byte{}, stop: make(chan struct{})}
+	as.instance = fmt.Sprintf("%d-%d", os.Getpid(), time.Now().UnixNano())
+	as.pstart, _ = osutil.ProcessStartId(os.Getpid())
+	as.filename = filepath.Join(autoSaveDir(), as.instance+".json")
+	go as.loop()
+	return as
+}
+
+// Saves and stops the periodic saving. Called from the ui goroutine.
+func (as *AutoSave) Close() {
+	close(as.stop)
+	as.Save()
+}
+
+func (as *AutoSave) loop() {
+	t := time.NewTicker(autoSaveInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-as.stop:
+			return
+		case <-t.C:
+			as.ed.UI.RunOnUIGoRoutine(as.Save)
+		}
+	}
+}
+
+//----------
+
+// Called from the ui goroutine.
+func (as *AutoSave) Save() {
+	if err := as.saveSession(); err != nil {
+		as.ed.Errorf("autosave: %v", err)
+	}
+	if err := as.journal(); err != nil {
+		as.ed.Errorf("autosave: %v", err)
+	}
+}
+
+func (as *AutoSave) saveSession() error {
+	s := NewSessionFromEditor(as.ed)
+	s.Name = AutoSaveSessionName
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(b, as.session) {
+		return nil
+	}
+	if err := os.MkdirAll(autoSaveDir(), 0700); err != nil {
+		return err
+	}
+	ss := &Sessions{Sessions: []*Session{s}}
+	if err := ss.save(as.filename); err != nil {
+		return err
+	}
+	if as.session == nil { // first save
+		if err := pruneAutoSaves(as.filename); err != nil {
+			return err
+		}
+	}
+	as.session = b
+	return nil
+}
+
+//----------
+
+func autoSaveDir() string {
+	return filepath.Join(osutil.HomeEnvVar(), ".editor_autosave")
+}
+
+// Autosave files, most recent first.
+func autoSaveFilenames() ([]string, error) {
+	fis, err := ioutil.ReadDir(autoSaveDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	sort.SliceStable(fis, func(a, b int) bool {
+		return fis[a].ModTime().After(fis[b].ModTime())
+	})
+	u := []string{}
+	for _, fi := range fis {
+		if strings.HasSuffix(fi.Name(), ".json") {
+			u = append(u, filepath.Join(autoSaveDir(), fi.Name()))
+		}
+	}
+	return u, nil
+}
+
+// Removes the older autosave files (keeps the given filename).
+func pruneAutoSaves(keep string) error {
+	u, err := autoSaveFilenames()
+	if err != nil {
+		return err
+	}
+	n := 0
+	for _, filename := range u {
+		if filename == keep {
+			continue
+		}
+		n++
+		if n < autoSaveKeep {
+			continue
+		}
+		if err := os.Remove(filename); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// The most recent autosave session, excluding the one in the given filename (ex: this instance).
+func latestAutoSaveSession(exclude string) (*Session, bool, error) {
+	u, err := autoSaveFilenames()
+	if err != nil {
+		return nil, false, err
+	}
+	for _, filename := range u {
+		if filename == exclude {
+			continue
+		}
+		ss, err := NewSessions(filename)
+		if err != nil {
+			continue // ex: partial file from another filesystem
+		}
+		if s, ok := ss.find(AutoSaveSessionName); ok {
+			return s, true, nil
+		}
+	}
+	return nil, false, nil
+}
+
+func (as *AutoSave) journal() error {
+	seen := map[int]bool{}
+	for _, info := range as.ed.ERowInfos() {
+		if !info.IsFileButNotDir() || len(info.ERows) == 0 {
+			continue
+		}
+		erow := info.ERows[0]
+		if !erow.Row.HasState(ui.RowStateEdited) {
+			continue
+		}
+		b, err := erow.Row.TextArea.Bytes()
+		if err != nil {
+			return err
+		}
+		id := recoveryId(as.instance, info.Name())
+		seen[id] = true
+		h := bytesHash(b)
+		if bytes.Equal(as.journaled[id], h) {
+			continue
+		}
+		e := &RecoveryEntry{Id: id, Pid: os.Getpid(), PStart: as.pstart, Filename: info.Name(), Time: time.Now(), Data: b}
+		if err := e.write(); err != nil {
+			return err
+		}
+		as.journaled[id] = h
+	}
+
+	// entries of files that were saved, or closed
+	for id := range as.journaled {
+		if !seen[id] {
+			delete(as.journaled, id)
+			if err := removeRecoveryEntry(id); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+//----------
+
+// Unsaved file content.
+type RecoveryEntry struct {
+	Id       int
+	Pid      int    // editor process that journaled the entry
+	PStart   string `json:",omitempty"` // process start id, detects a reused pid
+	Filename string
+	Time     time.Time
+	Data     []byte
+}
+
+// The entry is being kept by a running editor (this one or another instance), and is not available for recovery.
+func (e *RecoveryEntry) live() bool {
+	if e.Pid == 0 || !osutil.ProcessExists(e.Pid) {
+		return false
+	}
+	// the pid could have been reused (ex: after a reboot)
+	if id, ok := osutil.ProcessStartId(e.Pid); ok {
+		return id == e.PStart
+	}
+	return true
+}
+
+func (e *RecoveryEntry) write() error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	dir := recoveryDir()
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	// write to tmp file and rename to avoid a partial entry on crash
+	filename := recoveryEntryFilename(e.Id)
+	tmp := filename + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filename)
+}
+
+func readRecoveryEntry(id int) (*RecoveryEntry, error) {
+	b, err := ioutil.ReadFile(recoveryEntryFilename(id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("recovery entry not found: %v", id)
+		}
+		return nil, err
+	}
+	e := &RecoveryEntry{}
+	if err := json.Unmarshal(b, e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Entries available for recovery (the live ones are skipped), sorted by filename.
+func readRecoveryEntries() ([]*RecoveryEntry, error) {
+	fis, err := ioutil.ReadDir(recoveryDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	u := []*RecoveryEntry{}
+	for _, fi := range fis {
+		if !strings.HasSuffix(fi.Name(), ".json") {
+			continue
+		}
+		var id int
+		if _, err := fmt.Sscanf(fi.Name(), "%d.json", &id); err != nil {
+			continue
+		}
+		e, err := readRecoveryEntry(id)
+		if err != nil {
+			return nil, err
+		}
+		if e.live() {
+			continue
+		}
+		u = append(u, e)
+	}
+	sort.Slice(u, func(a, b int) bool {
+		return u[a].Filename < u[b].Filename
+	})
+	return u, nil
+}
+
+func removeRecoveryEntry(id int) error {
+	err := os.Remove(recoveryEntryFilename(id))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+//----------
+
+func recoveryDir() string {
+	return filepath.Join(osutil.HomeEnvVar(), ".editor_recovery")
+}
+
+func recoveryEntryFilename(id int) string {
+	return filepath.Join(recoveryDir(), fmt.Sprintf("%d.json", id))
+}
+
+// Ids are unique per editor instance, to not overwrite the entries of other instances (including previous ones with the same pid).
+func recoveryId(instance string, filename string) int {
+	h := fnv.New32a()
+	_, _ = fmt.Fprintf(h, "%v:%v", instance, filename)
+	return int(h.Sum32() & 0x7fffffff)
+}
+
+//----------
+
+// Row with the recovery entries list.
+const RecoveryRowName = "+Recovery"
+
+// Lists the recovery entries in a row.
+func ListRecovery(ed *Editor) {
+	entries, err := readRecoveryEntries()
+	if err != nil {
+		ed.Error(err)
+		return
+	}
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "recovery: %d unsaved files\n", len(entries))
+	for _, e := range entries {
+		fmt.Fprintf(buf, "RecoverDiff %[1]v RecoverRestore %[1]v RecoverDiscard %[1]v\n", e.Id)
+		t := e.Time.Format("2006-01-02 15:04:05")
+		fmt.Fprintf(buf, "\t%v: %v\n", t, ed.HomeVars.Encode(e.Filename))
+	}
+
+	erow, _ := ed.ExistingOrNewERow(RecoveryRowName)
+	erow.Row.TextArea.SetBytesClearPos(buf.Bytes())
+	erow.Flash()
+}
+
+// Updates the list if the row is open.
+func updateRecoveryList(ed *Editor) {
+	if info, ok := ed.ERowInfo(RecoveryRowName); ok && len(info.ERows) > 0 {
+		ListRecovery(ed)
+	}
+}
+
+// Fails if the entry is live.
+func readRecoverableEntry(id int) (*RecoveryEntry, error) {
+	e, err := readRecoveryEntry(id)
+	if err != nil {
+		return nil, err
+	}
+	if e.live() {
+		return nil, fmt.Errorf("recovery entry in use by a running editor (pid %v): %v", e.Pid, id)
+	}
+	return e, nil
+}
+
+// Shows the differences between the file on disk and the recovery content.
+func RecoverDiff(ed *Editor, id int) error {
+	e, err := readRecoverableEntry(id)
+	if err != nil {
+		return err
+	}
+	disk, err := fsys.ReadFile(e.Filename)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	d := diffutil.Unified(e.Filename, "recovery", disk, e.Data, 3)
+	if len(d) == 0 {
+		d = []byte("no differences\n")
+	}
+	erow, _ := ed.ExistingOrNewERow("+RecoveryDiff")
+	erow.Row.TextArea.SetBytesClearPos(d)
+	erow.Flash()
+	return nil
+}
+
+// Opens the file (if not open) and sets the recovery content (undoable).
+func RecoverRestore(ed *Editor, id int) error {
+	e, err := readRecoverableEntry(id)
+	if err != nil {
+		return err
+	}
+	info := ed.ReadERowInfo(e.Filename)
+	if !info.IsFileButNotDir() && !info.IsNotExist() {
+		return fmt.Errorf("not a file: %v", e.Filename)
+	}
+	if len(info.ERows) == 0 {
+		if _, err := info.NewERowCreateOnErr(ed.GoodRowPos()); err != nil {
+			ed.Error(err)
+		}
+	}
+	erow := info.ERows[0]
+	if err := erow.Row.TextArea.SetBytes(e.Data); err != nil {
+		return err
+	}
+	erow.Flash()
+
+	// will be journaled again while not saved
+	if err := removeRecoveryEntry(id); err != nil {
+		return err
+	}
+	updateRecoveryList(ed)
+	return nil
+}
+
+func RecoverDiscard(ed *Editor, id int) error {
+	if _, err := readRecoverableEntry(id); err != nil {
+		return err
+	}
+	if err := removeRecoveryEntry(id); err != nil {
+		return err
+	}
+	updateRecoveryList(ed)
+	return nil
+}
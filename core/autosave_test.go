@@ -0,0 +1,128 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jmigpin/editor/util/osutil"
+)
+
+func TestRecoveryEntries1(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "editor_recovery")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+	home := os.Getenv("HOME")
+	defer os.Setenv("HOME", home)
+	os.Setenv("HOME", tmpDir)
+
+	for _, name := range []string{"/b/c.txt", "/a/c.txt"} {
+		e := &RecoveryEntry{Id: recoveryId("", name), Filename: name, Time: time.Now(), Data: []byte(name)}
+		if err := e.write(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	u, err := readRecoveryEntries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(u) != 2 || u[0].Filename != "/a/c.txt" || !bytes.Equal(u[0].Data, []byte("/a/c.txt")) {
+		t.Fatalf("%v", u)
+	}
+
+	if err := removeRecoveryEntry(u[0].Id); err != nil {
+		t.Fatal(err)
+	}
+	if err := removeRecoveryEntry(u[0].Id); err != nil { // not existent
+		t.Fatal(err)
+	}
+	u, err = readRecoveryEntries()
+	if err != nil || len(u) != 1 {
+		t.Fatal(u, err)
+	}
+
+	// entry of a running editor (this process) is not listed or recoverable
+	name := "/b/c.txt"
+	pstart, _ := osutil.ProcessStartId(os.Getpid())
+	e := &RecoveryEntry{Id: recoveryId("i1", name), Pid: os.Getpid(), PStart: pstart, Filename: name, Time: time.Now()}
+	if err := e.write(); err != nil {
+		t.Fatal(err)
+	}
+	u, err = readRecoveryEntries()
+	if err != nil || len(u) != 1 || u[0].Pid != 0 {
+		t.Fatal(u, err)
+	}
+	if err := RecoverDiscard(nil, e.Id); err == nil {
+		t.Fatal("expecting error")
+	}
+	if _, err := readRecoveryEntry(e.Id); err != nil {
+		t.Fatal(err)
+	}
+
+	// same pid of a previous process (ex: after a reboot) is listed
+	if _, ok := osutil.ProcessStartId(os.Getpid()); ok {
+		e2 := &RecoveryEntry{Id: recoveryId("i2", name), Pid: os.Getpid(), PStart: "other", Filename: name, Time: time.Now()}
+		if err := e2.write(); err != nil {
+			t.Fatal(err)
+		}
+		u, err = readRecoveryEntries()
+		if err != nil || len(u) != 2 {
+			t.Fatal(u, err)
+		}
+	}
+}
+
+func TestAutoSaveFiles1(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "editor_autosave")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+	home := os.Getenv("HOME")
+	defer os.Setenv("HOME", home)
+	os.Setenv("HOME", tmpDir)
+
+	if err := os.MkdirAll(autoSaveDir(), 0700); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	names := []string{}
+	for i := 0; i < autoSaveKeep+2; i++ {
+		s := &Session{Name: AutoSaveSessionName, RootTbStr: fmt.Sprintf("s%d", i)}
+		filename := filepath.Join(autoSaveDir(), fmt.Sprintf("%d.json", i))
+		ss := &Sessions{Sessions: []*Session{s}}
+		if err := ss.save(filename); err != nil {
+			t.Fatal(err)
+		}
+		tm := now.Add(time.Duration(i) * time.Second)
+		if err := os.Chtimes(filename, tm, tm); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, filename)
+	}
+
+	// most recent of other instances
+	last := names[len(names)-1]
+	s, ok, err := latestAutoSaveSession(last)
+	if err != nil || !ok || s.RootTbStr != fmt.Sprintf("s%d", len(names)-2) {
+		t.Fatal(s, ok, err)
+	}
+
+	// older files are removed, the instance file is kept
+	if err := pruneAutoSaves(names[0]); err != nil {
+		t.Fatal(err)
+	}
+	u, err := autoSaveFilenames()
+	if err != nil || len(u) != autoSaveKeep {
+		t.Fatal(u, err)
+	}
+	if u[0] != last || u[len(u)-1] != names[0] {
+		t.Fatal(u)
+	}
+}
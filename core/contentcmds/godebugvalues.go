@@ -0,0 +1,24 @@
+package contentcmds
+
+import (
+	"context"
+
+	"github.com/jmigpin/editor/core"
+)
+
+// Expands/collapses a value in the godebug values row. Not handled if there is no value at the index (allows other cmds to run).
+func GoDebugToggleValue(ctx context.Context, erow *core.ERow, index int) (error, bool) {
+	if erow.Info.Name() != core.GoDebugValuesRowName {
+		return nil, false
+	}
+	c := make(chan bool, 1)
+	erow.Ed.UI.RunOnUIGoRoutine(func() {
+		c <- erow.Ed.GoDebug.ToggleValueAtIndex(erow, index)
+	})
+	select {
+	case <-ctx.Done():
+		return ctx.Err(), true
+	case ok := <-c:
+		return nil, ok
+	}
+}
@@ -4,9 +4,13 @@ import "github.com/jmigpin/editor/core"
 
 func init() {
 	// order matters
+	core.ContentCmds.Append("plumbing", Plumbing)
+	core.ContentCmds.Append("godebugvalues", GoDebugToggleValue)
 	core.ContentCmds.Append("gotodefinition", GoToDefinitionGolang)
 	core.ContentCmds.Append("gotodefinition_lsproto", GoToDefinitionLSProto)
 	core.ContentCmds.Append("openfilename", OpenFilename)
 	core.ContentCmds.Append("opensession", OpenSession)
+	core.ContentCmds.Append("jobcmd", JobCmd)
+	core.ContentCmds.Append("recoverycmd", RecoveryCmd)
 	core.ContentCmds.Append("openurl", OpenURL)
 }
@@ -0,0 +1,102 @@
+package contentcmds
+
+import (
+	"context"
+	"unicode"
+
+	"github.com/jmigpin/editor/core"
+	"github.com/jmigpin/editor/util/iout/iorw"
+	"github.com/jmigpin/editor/util/scanutil"
+)
+
+// Job cmds listed by the "Jobs" cmd: "JobShow 1 JobStop 1 JobKill 1". Only in the jobs row.
+func JobCmd(ctx context.Context, erow *core.ERow, index int) (error, bool) {
+	if erow.Info.Name() != core.JobsRowName {
+		return nil, false
+	}
+	ta := erow.Row.TextArea
+
+	// limit reading
+	rw := ta.TextCursor.RW()
+	rd := iorw.NewLimitedReader(rw, index, index, 100)
+
+	fns := map[string]func(*core.Editor, int) error{
+		"JobShow": core.JobShow,
+		"JobStop": core.JobStop,
+		"JobKill": core.JobKill,
+	}
+	return idCmd(erow, rd, index, fns)
+}
+
+// Runs the cmd with the "<name> <id>" format at the index.
+func idCmd(erow *core.ERow, rd iorw.Reader, index int, fns map[string]func(*core.Editor, int) error) (error, bool) {
+	names := []string{}
+	for name := range fns {
+		names = append(names, name)
+	}
+	name, id, err := idCmdAtIndex(rd, index, names)
+	if err != nil {
+		return nil, false
+	}
+
+	erow.Ed.UI.RunOnUIGoRoutine(func() {
+		if err := fns[name](erow.Ed, id); err != nil {
+			erow.Ed.Errorf("%v: %v", name, err)
+		}
+	})
+
+	return nil, true
+}
+
+func idCmdAtIndex(rd iorw.Reader, index int, names []string) (string, int, error) {
+	sc := scanutil.NewScanner(rd)
+	sc.SetStartPos(index)
+
+	// index at: "JobSh|ow 1"
+	sc.Reverse = true
+	_ = sc.Match.FnLoop(idCmdRune)
+	sc.Reverse = false
+
+	// index at: "|JobShow 1"
+	name, id, err := readIdCmd(sc, names)
+	if err == nil {
+		return name, id, nil
+	}
+
+	// index at: "JobShow |1"
+	sc.Reverse = true
+	if !sc.Match.Rune(' ') {
+		return "", 0, sc.Errorf("space")
+	}
+	_ = sc.Match.FnLoop(idCmdRune)
+	sc.Reverse = false
+
+	// index at: "|JobShow 1"
+	return readIdCmd(sc, names)
+}
+
+func readIdCmd(sc *scanutil.Scanner, names []string) (string, int, error) {
+	for _, name := range names {
+		var id int
+		ok := sc.RewindOnFalse(func() bool {
+			if !sc.Match.Sequence(name + " ") {
+				return false
+			}
+			sc.Advance()
+			v, err := sc.Match.IntValue()
+			if err != nil {
+				return false
+			}
+			id = v
+			return true
+		})
+		if ok {
+			return name, id, nil
+		}
+	}
+	return "", 0, sc.Errorf("not found")
+}
+
+func idCmdRune(ru rune) bool {
+	return unicode.IsLetter(ru) || unicode.IsDigit(ru)
+}
@@ -0,0 +1,21 @@
+package contentcmds
+
+import (
+	"testing"
+
+	"github.com/jmigpin/editor/util/iout/iorw"
+)
+
+func TestJobCmd1(t *testing.T) {
+	s := "JobShow 12 JobStop 12 JobKill 12"
+	rd := iorw.NewStringReader(s)
+	for i := 11; i <= 21; i++ {
+		name, id, err := idCmdAtIndex(rd, i, []string{"JobShow", "JobStop", "JobKill"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if name != "JobStop" || id != 12 {
+			t.Fatal(name, id)
+		}
+	}
+}
@@ -58,14 +58,18 @@ func OpenFilename(ctx context.Context, erow *core.ERow, index int) (error, bool)
 	// remove escapes
 	filePos.Filename = parseutil.RemoveFilenameEscapes(filePos.Filename, res.Escape, res.PathSep)
 
+	return openFilePos(erow, filePos), true
+}
+
+// Opens the filename (relative to the row dir) at the position.
+func openFilePos(erow *core.ERow, filePos *parseutil.FilePos) error {
 	// decode home vars
 	filePos.Filename = erow.Ed.HomeVars.Decode(filePos.Filename)
 
 	// find full filename
 	filename, fi, ok := core.FindFileInfo(filePos.Filename, erow.Info.Dir())
 	if !ok {
-		err := fmt.Errorf("fileinfo not found: %q", filePos.Filename)
-		return err, true
+		return fmt.Errorf("fileinfo not found: %q", filePos.Filename)
 	}
 	filePos.Filename = filename
 
@@ -88,5 +92,5 @@ func OpenFilename(ctx context.Context, erow *core.ERow, index int) (error, bool)
 		core.OpenFileERow(erow.Ed, conf) // needs ui goroutine
 	})
 
-	return nil, true
+	return nil
 }
@@ -48,12 +48,15 @@ func OpenURL(ctx context.Context, erow *core.ERow, index int) (error, bool) {
 		return err, false
 	}
 
+	return openURL(ctx, erow, u.String()), true
+}
+
+func openURL(ctx context.Context, erow *core.ERow, ustr string) error {
 	// cmd timeout
 	ctx2, cancel := context.WithTimeout(ctx, 2*time.Second)
 	defer cancel()
 
 	// cmd
-	ustr := u.String()
 	args := []string{"xdg-open", ustr}
 	cmd := osutil.ExecCmdCtxWithAttr(ctx2, args)
 
@@ -62,14 +65,14 @@ func OpenURL(ctx context.Context, erow *core.ERow, index int) (error, bool) {
 	cmd.Stderr = &out
 
 	if err := cmd.Start(); err != nil {
-		return err, true
+		return err
 	}
 
 	erow.Ed.Messagef("openurl:\n\t%v", strings.Join(args, " "))
 
-	err = cmd.Wait()
+	err := cmd.Wait()
 	if err != nil {
 		err = fmt.Errorf("%v: %v", err, out.String())
 	}
-	return err, true
+	return err
 }
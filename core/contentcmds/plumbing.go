@@ -0,0 +1,80 @@
+package contentcmds
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/jmigpin/editor/core"
+	"github.com/jmigpin/editor/core/parseutil"
+	"github.com/jmigpin/editor/core/plumbing"
+	"github.com/jmigpin/editor/util/iout/iorw"
+	"github.com/jmigpin/editor/util/osutil"
+)
+
+var plumbingRules = plumbing.NewRulesFile(filepath.Join(osutil.HomeEnvVar(), ".editor_plumbing"))
+
+// Runs the action of the first plumbing rule that matches the text at the index (rules from ~/.editor_plumbing).
+func Plumbing(ctx context.Context, erow *core.ERow, index int) (error, bool) {
+	rules, err := plumbingRules.Rules()
+	if err != nil {
+		return err, true
+	}
+	if len(rules) == 0 {
+		return nil, false
+	}
+
+	text, start, err := plumbingText(erow, index)
+	if err != nil {
+		return err, false
+	}
+	res, ok := plumbing.Match(rules, text, index-start, erow.Info.Name())
+	if !ok {
+		return nil, false
+	}
+
+	switch res.Rule.Action {
+	case plumbing.OpenAction:
+		filePos, err := parseutil.ParseFilePos(res.Arg)
+		if err != nil {
+			return fmt.Errorf("plumbing: line %v: %v", res.Rule.Line, err), true
+		}
+		return openFilePos(erow, filePos), true
+	case plumbing.RunAction:
+		args := osutil.ShellRunArgs(res.Arg)
+		erow.Ed.UI.RunOnUIGoRoutine(func() {
+			core.ExternalCmdFromArgsNewRow(erow, args, nil)
+		})
+		return nil, true
+	case plumbing.URLAction:
+		return openURL(ctx, erow, res.Arg), true
+	}
+	return fmt.Errorf("plumbing: unexpected action: %v", res.Rule.Action), true
+}
+
+// Returns the selection if on, or the line at the index.
+func plumbingText(erow *core.ERow, index int) ([]byte, int, error) {
+	tc := erow.Row.TextArea.TextCursor
+	if tc.SelectionOn() {
+		a, b := tc.SelectionIndexes()
+		if index >= a && index <= b {
+			p, err := tc.RW().ReadNCopyAt(a, b-a)
+			return p, a, err
+		}
+	}
+
+	// limit reading
+	rd := iorw.NewLimitedReader(tc.RW(), index, index, 1000)
+	a, b, newline, err := iorw.LinesIndexes(rd, index, index)
+	if err != nil {
+		return nil, 0, err
+	}
+	if a < rd.Min() {
+		a = rd.Min()
+	}
+	if newline {
+		b--
+	}
+	p, err := rd.ReadNCopyAt(a, b-a)
+	return p, a, err
+}
@@ -0,0 +1,27 @@
+package contentcmds
+
+import (
+	"context"
+
+	"github.com/jmigpin/editor/core"
+	"github.com/jmigpin/editor/util/iout/iorw"
+)
+
+// Recovery cmds listed by the "Recovery" cmd: "RecoverDiff 1 RecoverRestore 1 RecoverDiscard 1". Only in the recovery row.
+func RecoveryCmd(ctx context.Context, erow *core.ERow, index int) (error, bool) {
+	if erow.Info.Name() != core.RecoveryRowName {
+		return nil, false
+	}
+	ta := erow.Row.TextArea
+
+	// limit reading
+	rw := ta.TextCursor.RW()
+	rd := iorw.NewLimitedReader(rw, index, index, 100)
+
+	fns := map[string]func(*core.Editor, int) error{
+		"RecoverDiff":    core.RecoverDiff,
+		"RecoverRestore": core.RecoverRestore,
+		"RecoverDiscard": core.RecoverDiscard,
+	}
+	return idCmd(erow, rd, index, fns)
+}
@@ -0,0 +1,159 @@
+// Protocol of the editor control socket.
+//
+// A request is a line with the operation and the path, followed by the data length and data for writes:
+// 	read <path>\n
+// 	write <path> <n>\n<n bytes>
+// A response is a line with the data length followed by the data, or an error line:
+// 	ok <n>\n<n bytes>
+// 	error <msg>\n
+// Reading an "event" path responds with "ok stream\n" followed by event lines until the connection is closed.
+//
+// Paths:
+// 	index: rows list, one per line: "<id> <name>"
+// 	new: write a filename or directory to open a new row (responds with the row id)
+// 	ctl: write a cmd to run it on the top toolbar
+// 	event: editor events
+// 	<id>/body: row text
+// 	<id>/tag: row toolbar
+// 	<id>/addr: row selection (or cursor) as "<start> <end>" byte offsets
+// 	<id>/ctl: read the row info, write a cmd to run it on the row toolbar
+// 	<id>/event: row events
+package ctlproto
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Environment variable with the socket address. Set for the external cmds run by the editor.
+const SocketEnv = "EDITOR_CTL"
+
+const StreamStr = "stream"
+
+// Max length of the data of a request or response.
+const MaxDataSize = 256 << 20
+
+//----------
+
+type Request struct {
+	Op   string // "read", "write"
+	Path string
+	Data []byte
+}
+
+func WriteRequest(w io.Writer, req *Request) error {
+	switch req.Op {
+	case "read":
+		_, err := fmt.Fprintf(w, "read %s\n", req.Path)
+		return err
+	case "write":
+		if _, err := fmt.Fprintf(w, "write %s %d\n", req.Path, len(req.Data)); err != nil {
+			return err
+		}
+		_, err := w.Write(req.Data)
+		return err
+	default:
+		return fmt.Errorf("unknown op: %q", req.Op)
+	}
+}
+
+func ReadRequest(br *bufio.Reader) (*Request, error) {
+	line, err := readLine(br)
+	if err != nil {
+		return nil, err
+	}
+	u := strings.Fields(line)
+	if len(u) < 2 {
+		return nil, fmt.Errorf("bad request: %q", line)
+	}
+	req := &Request{Op: u[0], Path: u[1]}
+	switch req.Op {
+	case "read":
+		if len(u) != 2 {
+			return nil, fmt.Errorf("bad request: %q", line)
+		}
+	case "write":
+		if len(u) != 3 {
+			return nil, fmt.Errorf("bad request: %q", line)
+		}
+		req.Data, err = readData(br, u[2])
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown op: %q", req.Op)
+	}
+	return req, nil
+}
+
+//----------
+
+func WriteResponse(w io.Writer, data []byte, err error) error {
+	if err != nil {
+		// single line
+		s := strings.Replace(err.Error(), "\n", " ", -1)
+		_, err := fmt.Fprintf(w, "error %s\n", s)
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "ok %d\n", len(data)); err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+func WriteStreamResponse(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "ok %s\n", StreamStr)
+	return err
+}
+
+// Returns stream=true if the data follows as lines until the connection is closed.
+func ReadResponse(br *bufio.Reader) (data []byte, stream bool, _ error) {
+	line, err := readLine(br)
+	if err != nil {
+		return nil, false, err
+	}
+	if s := strings.TrimPrefix(line, "error "); s != line {
+		return nil, false, fmt.Errorf("%s", s)
+	}
+	s := strings.TrimPrefix(line, "ok ")
+	if s == line {
+		return nil, false, fmt.Errorf("bad response: %q", line)
+	}
+	if s == StreamStr {
+		return nil, true, nil
+	}
+	data, err = readData(br, s)
+	return data, false, err
+}
+
+//----------
+
+func readLine(br *bufio.Reader) (string, error) {
+	line, err := br.ReadString('\n')
+	if err != nil {
+		if err == io.EOF && line != "" {
+			err = io.ErrUnexpectedEOF
+		}
+		return "", err
+	}
+	return strings.TrimSuffix(line, "\n"), nil
+}
+
+func readData(br *bufio.Reader, nStr string) ([]byte, error) {
+	n, err := strconv.Atoi(nStr)
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("bad length: %q", nStr)
+	}
+	if n > MaxDataSize {
+		return nil, fmt.Errorf("data too big: %v > %v", n, MaxDataSize)
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(br, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
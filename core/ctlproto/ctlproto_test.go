@@ -0,0 +1,60 @@
+package ctlproto
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestRequest1(t *testing.T) {
+	buf := &bytes.Buffer{}
+	reqs := []*Request{
+		{Op: "read", Path: "index"},
+		{Op: "write", Path: "1/body", Data: []byte("a\nb\n")},
+		{Op: "write", Path: "1/addr", Data: []byte("")},
+	}
+	for _, req := range reqs {
+		if err := WriteRequest(buf, req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	br := bufio.NewReader(buf)
+	for _, req := range reqs {
+		req2, err := ReadRequest(br)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if req2.Op != req.Op || req2.Path != req.Path || !bytes.Equal(req2.Data, req.Data) {
+			t.Fatalf("%+v != %+v", req2, req)
+		}
+	}
+}
+
+func TestRequestMaxData(t *testing.T) {
+	buf := bytes.NewBufferString(fmt.Sprintf("write 1/body %d\n", MaxDataSize+1))
+	if _, err := ReadRequest(bufio.NewReader(buf)); err == nil {
+		t.Fatal("expecting error")
+	}
+}
+
+func TestResponse1(t *testing.T) {
+	buf := &bytes.Buffer{}
+	_ = WriteResponse(buf, []byte("abc\n"), nil)
+	_ = WriteResponse(buf, nil, fmt.Errorf("row\nnot found"))
+	_ = WriteStreamResponse(buf)
+
+	br := bufio.NewReader(buf)
+	data, _, err := ReadResponse(br)
+	if err != nil || string(data) != "abc\n" {
+		t.Fatal(data, err)
+	}
+	_, _, err = ReadResponse(br)
+	if err == nil || err.Error() != "row not found" {
+		t.Fatal(err)
+	}
+	_, stream, err := ReadResponse(br)
+	if err != nil || !stream {
+		t.Fatal(stream, err)
+	}
+}
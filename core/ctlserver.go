@@ -0,0 +1,361 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jmigpin/editor/core/ctlproto"
+	"github.com/jmigpin/editor/core/toolbarparser"
+	"github.com/jmigpin/editor/util/evreg"
+	"github.com/jmigpin/editor/util/osutil"
+)
+
+// Local unix socket that allows external programs to control the editor (see ctlproto pkg).
+type CtlServer struct {
+	ed      *Editor
+	Addr    string
+	ln      net.Listener
+	privDir string // removed on close
+}
+
+// The socket is only accessible by the user: created in "$XDG_RUNTIME_DIR" (private by spec), or in a new private temporary dir.
+func NewCtlServer(ed *Editor) (*CtlServer, error) {
+	cs := &CtlServer{ed: ed}
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" && isPrivateDir(dir) {
+		name := fmt.Sprintf("editor_ctl_%d.sock", os.Getpid())
+		cs.Addr = filepath.Join(dir, name)
+		_ = os.Remove(cs.Addr) // old socket from a process with the same pid
+	} else {
+		dir, err := ioutil.TempDir("", "editor_ctl") // mode 0700
+		if err != nil {
+			return nil, err
+		}
+		cs.privDir = dir
+		cs.Addr = filepath.Join(dir, "ctl.sock")
+	}
+
+	ln, err := net.Listen("unix", cs.Addr)
+	if err != nil {
+		cs.removePrivDir()
+		return nil, err
+	}
+	cs.ln = ln
+	if err := os.Chmod(cs.Addr, 0600); err != nil {
+		cs.Close()
+		return nil, err
+	}
+
+	// available to the external cmds
+	if err := os.Setenv(ctlproto.SocketEnv, cs.Addr); err != nil {
+		cs.Close()
+		return nil, err
+	}
+
+	go cs.acceptLoop()
+	return cs, nil
+}
+
+func (cs *CtlServer) Close() error {
+	err := cs.ln.Close() // also removes the socket file
+	cs.removePrivDir()
+	return err
+}
+
+func (cs *CtlServer) removePrivDir() {
+	if cs.privDir != "" {
+		_ = os.RemoveAll(cs.privDir)
+	}
+}
+
+// Dir owned by the user and not accessible by others.
+func isPrivateDir(dir string) bool {
+	fi, err := os.Stat(dir)
+	if err != nil || !fi.IsDir() {
+		return false
+	}
+	return fi.Mode().Perm()&0077 == 0 && osutil.IsOwnedByUser(fi)
+}
+
+//----------
+
+func (cs *CtlServer) acceptLoop() {
+	for {
+		conn, err := cs.ln.Accept()
+		if err != nil {
+			return // closed
+		}
+		go cs.handleConn(conn)
+	}
+}
+
+func (cs *CtlServer) handleConn(conn net.Conn) {
+	defer conn.Close()
+	br := bufio.NewReader(conn)
+	for {
+		req, err := ctlproto.ReadRequest(br)
+		if err != nil {
+			if err != io.EOF {
+				_ = ctlproto.WriteResponse(conn, nil, err)
+			}
+			return
+		}
+
+		// event stream keeps the connection until it is closed
+		if req.Op == "read" && (req.Path == "event" || strings.HasSuffix(req.Path, "/event")) {
+			cs.streamEvents(conn, br, req.Path)
+			return
+		}
+
+		var data []byte
+		cs.ed.UI.WaitRunOnUIGoRoutine(func() {
+			data, err = cs.handleReq(req)
+		})
+		if err := ctlproto.WriteResponse(conn, data, err); err != nil {
+			return
+		}
+	}
+}
+
+//----------
+
+// Runs on the ui goroutine.
+func (cs *CtlServer) handleReq(req *ctlproto.Request) ([]byte, error) {
+	read := req.Op == "read"
+	switch req.Path {
+	case "index":
+		if !read {
+			return nil, fmt.Errorf("read only")
+		}
+		return cs.index(), nil
+	case "new":
+		if read {
+			return nil, fmt.Errorf("write only")
+		}
+		return cs.newERow(string(req.Data))
+	case "ctl":
+		if read {
+			return nil, fmt.Errorf("write only")
+		}
+		return nil, cs.runCmd(nil, string(req.Data))
+	}
+
+	// row paths
+	u := strings.SplitN(req.Path, "/", 2)
+	if len(u) != 2 {
+		return nil, fmt.Errorf("bad path: %v", req.Path)
+	}
+	erow, err := cs.erow(u[0])
+	if err != nil {
+		return nil, err
+	}
+	switch u[1] {
+	case "body":
+		if read {
+			return erow.Row.TextArea.Bytes()
+		}
+		return nil, erow.Row.TextArea.SetBytes(req.Data)
+	case "tag":
+		if read {
+			arg, ok := erow.TbData.Part0Arg0()
+			if !ok {
+				return nil, fmt.Errorf("unable to get toolbar name")
+			}
+			return []byte(erow.Row.Toolbar.Str()[arg.End:]), nil
+		}
+		erow.ToolbarSetStrAfterNameClearHistory(string(req.Data))
+		return nil, nil
+	case "addr":
+		if read {
+			tc := erow.Row.TextArea.TextCursor
+			s, e := tc.Index(), tc.Index()
+			if tc.SelectionOn() {
+				s, e = tc.SelectionIndexes()
+			}
+			return []byte(fmt.Sprintf("%d %d", s, e)), nil
+		}
+		return nil, cs.setAddr(erow, string(req.Data))
+	case "ctl":
+		if read {
+			return cs.erowInfoLine(erow), nil
+		}
+		return nil, cs.runCmd(erow, string(req.Data))
+	}
+	return nil, fmt.Errorf("bad path: %v", req.Path)
+}
+
+//----------
+
+func (cs *CtlServer) erows() []*ERow {
+	u := []*ERow{}
+	for _, info := range cs.ed.ERowInfos() {
+		u = append(u, info.ERows...)
+	}
+	sort.Slice(u, func(a, b int) bool {
+		return u[a].Id < u[b].Id
+	})
+	return u
+}
+
+func (cs *CtlServer) erow(idStr string) (*ERow, error) {
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		return nil, fmt.Errorf("bad row id: %q", idStr)
+	}
+	for _, erow := range cs.erows() {
+		if erow.Id == id {
+			return erow, nil
+		}
+	}
+	return nil, fmt.Errorf("row not found: %v", id)
+}
+
+//----------
+
+func (cs *CtlServer) index() []byte {
+	buf := &bytes.Buffer{}
+	for _, erow := range cs.erows() {
+		fmt.Fprintf(buf, "%d %s\n", erow.Id, erow.Info.Name())
+	}
+	return buf.Bytes()
+}
+
+func (cs *CtlServer) erowInfoLine(erow *ERow) []byte {
+	s := fmt.Sprintf("%d %s %v\n", erow.Id, erow.Info.Name(), erow.Row.State())
+	return []byte(s)
+}
+
+func (cs *CtlServer) newERow(name string) ([]byte, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("missing name")
+	}
+	info := cs.ed.ReadERowInfo(name)
+	erow, err := info.NewERow(cs.ed.GoodRowPos())
+	if err != nil {
+		return nil, err
+	}
+	erow.Flash()
+	return []byte(strconv.Itoa(erow.Id)), nil
+}
+
+func (cs *CtlServer) setAddr(erow *ERow, s string) error {
+	u := strings.Fields(s)
+	if len(u) != 2 {
+		return fmt.Errorf("expecting \"<start> <end>\"")
+	}
+	start, err := strconv.Atoi(u[0])
+	if err != nil {
+		return err
+	}
+	end, err := strconv.Atoi(u[1])
+	if err != nil {
+		return err
+	}
+	ta := erow.Row.TextArea
+	if start < 0 || end < start || end > ta.Len() {
+		return fmt.Errorf("bad range: %v %v", start, end)
+	}
+	ta.TextCursor.SetSelection(start, end)
+	erow.MakeRangeVisibleAndFlash(start, end-start)
+	return nil
+}
+
+// Runs the cmd as if it was clicked on the row toolbar (or the top toolbar if erow is nil).
+func (cs *CtlServer) runCmd(erow *ERow, s string) error {
+	data := toolbarparser.Parse(strings.TrimSpace(s))
+	if len(data.Parts) == 0 || len(data.Parts[0].Args) == 0 {
+		return fmt.Errorf("missing cmd")
+	}
+	internalCmd(cs.ed, data.Parts[0], erow)
+	return nil
+}
+
+//----------
+
+func (cs *CtlServer) streamEvents(conn net.Conn, br *bufio.Reader, path string) {
+	// optional row filter
+	var erow *ERow
+	if path != "event" {
+		var err error
+		cs.ed.UI.WaitRunOnUIGoRoutine(func() {
+			erow, err = cs.erow(strings.TrimSuffix(path, "/event"))
+		})
+		if err != nil {
+			_ = ctlproto.WriteResponse(conn, nil, err)
+			return
+		}
+	}
+
+	// events are dropped if the client doesn't keep up (don't block the ui)
+	ch := make(chan string, 256)
+	send := func(s string) {
+		select {
+		case ch <- s:
+		default:
+		}
+	}
+	match := func(e *ERow) bool {
+		return erow == nil || erow == e
+	}
+
+	unr := &evreg.Unregister{}
+	cs.ed.UI.WaitRunOnUIGoRoutine(func() {
+		eevs := cs.ed.EEvents
+		unr.Add(eevs.Register(PostNewERowEEventId, func(ev0 interface{}) {
+			ev := ev0.(*PostNewERowEEvent)
+			if match(ev.ERow) {
+				send(fmt.Sprintf("new %d %s", ev.ERow.Id, ev.ERow.Info.Name()))
+			}
+		}))
+		unr.Add(eevs.Register(PreRowCloseEEventId, func(ev0 interface{}) {
+			ev := ev0.(*PreRowCloseEEvent)
+			if match(ev.ERow) {
+				send(fmt.Sprintf("close %d %s", ev.ERow.Id, ev.ERow.Info.Name()))
+			}
+		}))
+		unr.Add(eevs.Register(PostFileSaveEEventId, func(ev0 interface{}) {
+			ev := ev0.(*PostFileSaveEEvent)
+			if erow == nil || erow.Info == ev.Info {
+				send(fmt.Sprintf("save %s", ev.Info.Name()))
+			}
+		}))
+		unr.Add(eevs.Register(RowStateChangeEEventId, func(ev0 interface{}) {
+			ev := ev0.(*RowStateChangeEEvent)
+			if match(ev.ERow) {
+				send(fmt.Sprintf("state %d %v %v", ev.ERow.Id, ev.State, ev.Value))
+			}
+		}))
+	})
+	defer cs.ed.UI.RunOnUIGoRoutine(unr.UnregisterAll)
+
+	if err := ctlproto.WriteStreamResponse(conn); err != nil {
+		return
+	}
+
+	// detect the client closing the connection
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = io.Copy(ioutil.Discard, br)
+	}()
+
+	for {
+		select {
+		case <-done:
+			return
+		case s := <-ch:
+			if _, err := fmt.Fprintln(conn, s); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCtlServerPerm(t *testing.T) {
+	old := os.Getenv("XDG_RUNTIME_DIR")
+	defer os.Setenv("XDG_RUNTIME_DIR", old)
+	os.Setenv("XDG_RUNTIME_DIR", os.TempDir()) // not private, not used
+
+	cs, err := NewCtlServer(&Editor{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Dir(cs.Addr)
+	for _, u := range []struct {
+		name string
+		perm os.FileMode
+	}{{dir, 0700}, {cs.Addr, 0600}} {
+		fi, err := os.Stat(u.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fi.Mode().Perm() != u.perm {
+			t.Fatalf("%v: %v", u.name, fi.Mode())
+		}
+	}
+	if err := cs.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Fatal("private dir not removed")
+	}
+}
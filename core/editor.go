@@ -8,8 +8,10 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/jmigpin/editor/core/fswatcher"
+	"github.com/jmigpin/editor/core/fsys"
 	"github.com/jmigpin/editor/core/lsproto"
 	"github.com/jmigpin/editor/ui"
 	"github.com/jmigpin/editor/util/drawutil"
@@ -23,17 +25,22 @@ import (
 type Editor struct {
 	UI                *ui.UI
 	HomeVars          *HomeVars
-	Watcher           fswatcher.Watcher
+	EnvProfiles       *EnvProfiles
+	Watcher           *fswatcher.GWatcher
 	RowReopener       *RowReopener
 	GoDebug           *GoDebugInstance
 	LSProtoMan        *lsproto.Manager
 	InlineComplete    *InlineComplete
 	Plugins           *Plugins
 	EEvents           *EEvents // editor events (used by plugins)
-	FsCaseInsensitive bool     // filesystem
+	AutoSave          *AutoSave
+	FsCaseInsensitive bool // filesystem
+	SaveBackups       bool // keep "file~" backups on save
+	AutoReload        bool // reload unedited rows on disk changes
 
 	dndh *DndHandler
 	ifbw *InfoFloatBoxWrap
+	ctl  *CtlServer
 
 	erowInfos map[string]*ERowInfo // use ed.ERowInfo*() to access
 }
@@ -45,8 +52,11 @@ func NewEditor(opt *Options) (*Editor, error) {
 
 	// TODO: osx can have a case insensitive filesystem
 	ed.FsCaseInsensitive = runtime.GOOS == "windows"
+	ed.SaveBackups = opt.SaveBackups
+	ed.AutoReload = opt.AutoReload
 
 	ed.HomeVars = NewHomeVars()
+	ed.EnvProfiles = NewEnvProfiles()
 	ed.RowReopener = NewRowReopener(ed)
 	ed.dndh = NewDndHandler(ed)
 	ed.GoDebug = NewGoDebugInstance(ed)
@@ -67,13 +77,17 @@ func NewEditor(opt *Options) (*Editor, error) {
 
 //----------
 
+// Interval of the polling used for the paths that can't be watched.
+const watcherPollInterval = 2 * time.Second
+
 func (ed *Editor) init(opt *Options) error {
-	// fs watcher + gwatcher
+	// fs watcher + polling fallback (ex: inotify watches limit) + gwatcher
 	w, err := fswatcher.NewFsnWatcher()
 	if err != nil {
 		return err
 	}
-	ed.Watcher = fswatcher.NewGWatcher(w)
+	pw := fswatcher.NewPollWatcher(watcherPollInterval)
+	ed.Watcher = fswatcher.NewGWatcher(fswatcher.NewFallbackWatcher(w, pw))
 
 	ed.setupTheme(opt)
 	event.UseMultiKey = opt.UseMultiKey
@@ -89,6 +103,12 @@ func (ed *Editor) init(opt *Options) error {
 	ed.setupRootToolbar()
 	ed.setupRootMenuToolbar()
 
+	// control socket (not fatal)
+	ed.ctl, err = NewCtlServer(ed)
+	if err != nil {
+		ed.Errorf("ctl server: %v", err)
+	}
+
 	// TODO: ensure it has the window measure
 	ed.EnsureOneColumn()
 
@@ -107,12 +127,27 @@ func (ed *Editor) init(opt *Options) error {
 		})
 	}
 
+	// offer to restore unsaved files from a previous run
+	ed.UI.RunOnUIGoRoutine(func() {
+		if u, err := readRecoveryEntries(); err == nil && len(u) > 0 {
+			ListRecovery(ed)
+		}
+	})
+	ed.AutoSave = NewAutoSave(ed)
+
 	return nil
 }
 
 func (ed *Editor) initLSProto(opt *Options) {
 	// language server protocol manager
 	ed.LSProtoMan = lsproto.NewManager(ed.Error)
+	ed.LSProtoMan.EnvFn = func(filename string) []string {
+		env, err := ed.EnvProfiles.FileEnviron(filename)
+		if err != nil {
+			ed.Error(err)
+		}
+		return env
+	}
 	for _, reg := range opt.LSProtos.regs {
 		ed.LSProtoMan.Register(reg)
 	}
@@ -129,6 +164,11 @@ func (ed *Editor) Close() {
 
 func (ed *Editor) uiEventLoop() {
 	defer ed.UI.Close()
+	defer func() {
+		if ed.ctl != nil {
+			_ = ed.ctl.Close()
+		}
+	}()
 
 	for {
 		ev := ed.UI.NextEvent()
@@ -137,8 +177,10 @@ func (ed *Editor) uiEventLoop() {
 			log.Println(t) // in case there is no window yet
 			ed.Error(t)
 		case *editorClose:
+			ed.AutoSave.Close()
 			return
 		case *event.WindowClose:
+			ed.AutoSave.Close()
 			return
 		case *event.DndPosition:
 			ed.dndh.OnPosition(t)
@@ -182,6 +224,13 @@ func (ed *Editor) handleWatcherEvent(ev *fswatcher.Event) {
 			info.UpdateDiskEvent()
 		})
 	}
+	ed.UI.RunOnUIGoRoutine(func() {
+		for _, info := range ed.ERowInfos() {
+			for _, erow := range info.ERows {
+				erow.Watch.handleEvent(ev)
+			}
+		}
+	})
 }
 
 //----------
@@ -302,6 +351,15 @@ func (ed *Editor) ActiveERow() (*ERow, bool) {
 	return nil, false
 }
 
+func (ed *Editor) rowERow(row *ui.Row) (*ERow, bool) {
+	for _, erow := range ed.ERows() {
+		if erow.Row == row {
+			return erow, true
+		}
+	}
+	return nil, false
+}
+
 //----------
 
 func (ed *Editor) setupRootToolbar() {
@@ -357,6 +415,7 @@ func (ed *Editor) updateERowsToolbarsHomeVars() {
 	tb1 := ed.UI.Root.Toolbar.Str()
 	tb2 := ed.UI.Root.MainMenuButton.Toolbar.Str()
 	ed.HomeVars.ParseToolbarVars([]string{tb1, tb2}, ed.FsCaseInsensitive)
+	ed.EnvProfiles.ParseToolbarVars([]string{tb1, tb2})
 	for _, erow := range ed.ERows() {
 		erow.updateToolbarPart0()
 	}
@@ -375,9 +434,11 @@ func (ed *Editor) setupInitialRows(opt *Options) {
 		col := ed.UI.Root.Cols.FirstChildColumn()
 		for _, filename := range opt.Filenames {
 			// try to use absolute path
-			u, err := filepath.Abs(filename)
-			if err == nil {
-				filename = u
+			if fsys.IsLocal(filename) {
+				u, err := filepath.Abs(filename)
+				if err == nil {
+					filename = u
+				}
 			}
 
 			info := ed.ReadERowInfo(filename)
@@ -791,6 +852,9 @@ type Options struct {
 
 	UseMultiKey bool
 
+	SaveBackups bool
+	AutoReload  bool
+
 	Plugins string
 
 	LSProtos RegistrationsOpt
@@ -0,0 +1,257 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/jmigpin/editor/core/fsys"
+	"github.com/jmigpin/editor/core/toolbarparser"
+	"github.com/jmigpin/editor/util/osutil"
+)
+
+const EnvProfilesFilename = ".editorenv"
+
+// Named environments for the external cmds, GoDebug and LSP servers.
+// Defined in the root toolbar (ex: $env_arm="GOOS=linux GOARCH=arm"), or in ".editorenv" files in the directory hierarchy. The root toolbar "$env=<name>" sets the default profile.
+// The ".editorenv" files are only used after being allowed (AllowEnv), and need to be allowed again when their content changes.
+type EnvProfiles struct {
+	mu           sync.RWMutex
+	def          string              // default profile name
+	profiles     map[string][]string // root toolbar profiles
+	fileProfiles map[string]string   // row filename -> row "$env" profile name
+
+	allowedFilename string // list of allowed ".editorenv" files
+}
+
+func NewEnvProfiles() *EnvProfiles {
+	ep := &EnvProfiles{}
+	ep.fileProfiles = map[string]string{}
+	ep.allowedFilename = filepath.Join(osutil.HomeEnvVar(), ".editor_env_allowed")
+	return ep
+}
+
+func (ep *EnvProfiles) ParseToolbarVars(strs []string) {
+	def := ""
+	profiles := map[string][]string{}
+	for _, str := range strs {
+		data := toolbarparser.Parse(str)
+		for k, v := range toolbarparser.ParseVars(data) {
+			if k == "$env" {
+				def = v
+			} else if name := strings.TrimPrefix(k, "$env_"); name != k {
+				profiles[name] = strings.Fields(v)
+			}
+		}
+	}
+
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+	ep.def = def
+	ep.profiles = profiles
+}
+
+// Keeps the "$env" profile of the row of the filename, used by the environment of the LSP servers started for the file. An empty name clears it. Safe to use concurrently.
+func (ep *EnvProfiles) SetFileProfile(filename, name string) {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+	if name == "" {
+		delete(ep.fileProfiles, filename)
+		return
+	}
+	ep.fileProfiles[filename] = name
+}
+
+// Environment for cmds started for the filename (ex: LSP servers), with the profile of the filename row.
+func (ep *EnvProfiles) FileEnviron(filename string) ([]string, error) {
+	ep.mu.RLock()
+	name := ep.fileProfiles[filename]
+	ep.mu.RUnlock()
+	dir := ""
+	if fsys.IsLocal(filename) {
+		dir = filepath.Dir(filename)
+	}
+	return ep.Environ(dir, name)
+}
+
+//----------
+
+// Returns the process environment with the ".editorenv" defaults of the dir hierarchy and the named profile. An empty name uses the root toolbar default profile, if any. The ".editorenv" files that are not allowed are skipped (the env is still returned along with the error). The files are not looked for if the dir is empty or remote (ex: "ssh://host/path"). Safe to use concurrently.
+func (ep *EnvProfiles) Environ(dir, name string) ([]string, error) {
+	env := os.Environ()
+
+	var files []envFile
+	var notAllowed []string
+	var err error
+	if dir != "" && fsys.IsLocal(dir) {
+		files, notAllowed, err = readEnvFiles(dir, ep.allowed())
+		if err != nil {
+			return env, err
+		}
+	}
+	env, err = ep.environ(env, files, name)
+	if err == nil && len(notAllowed) > 0 {
+		err = fmt.Errorf("env file not allowed (use AllowEnv), skipped: %v", strings.Join(notAllowed, ", "))
+	}
+	return env, err
+}
+
+func (ep *EnvProfiles) environ(env []string, files []envFile, name string) ([]string, error) {
+	// defaults: nearest dir has priority
+	for i := len(files) - 1; i >= 0; i-- {
+		env = setEnvsExpand(env, files[i][""])
+	}
+
+	ep.mu.RLock()
+	defer ep.mu.RUnlock()
+
+	if name == "" {
+		name = ep.def
+	}
+	if name == "" {
+		return env, nil
+	}
+
+	// profile: nearest dir, then root toolbar
+	for _, f := range files {
+		if vars, ok := f[name]; ok {
+			return setEnvsExpand(env, vars), nil
+		}
+	}
+	if vars, ok := ep.profiles[name]; ok {
+		return setEnvsExpand(env, vars), nil
+	}
+	return env, fmt.Errorf("env profile not found: %q", name)
+}
+
+//----------
+
+// Profile name -> vars. The "" profile has the vars defined before any profile.
+type envFile map[string][]string
+
+// Returns the env files from dir up to the root, nearest first, and the filenames of the ones not allowed.
+func readEnvFiles(dir string, allowed map[string]string) ([]envFile, []string, error) {
+	files := []envFile{}
+	notAllowed := []string{}
+	for d := dir; d != ""; {
+		filename := filepath.Join(d, EnvProfilesFilename)
+		b, err := ioutil.ReadFile(filename)
+		if err == nil && allowed[filename] != envFileHash(b) {
+			notAllowed = append(notAllowed, filename)
+		} else if err == nil {
+			f, err := parseEnvFile(b)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%v: %v", filename, err)
+			}
+			files = append(files, f)
+		}
+
+		d2 := filepath.Dir(d)
+		if d2 == d {
+			break
+		}
+		d = d2
+	}
+	return files, notAllowed, nil
+}
+
+// Format:
+//
+//	# vars always applied
+//	KEY=value
+//	# vars applied if the profile is selected
+//	[name]
+//	KEY=value
+func parseEnvFile(src []byte) (envFile, error) {
+	f := envFile{}
+	name := ""
+	sc := bufio.NewScanner(bytes.NewReader(src))
+	for line := 1; sc.Scan(); line++ {
+		s := strings.TrimSpace(sc.Text())
+		if s == "" || strings.HasPrefix(s, "#") {
+			continue
+		}
+		if strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]") {
+			name = strings.TrimSpace(s[1 : len(s)-1])
+			if name == "" {
+				return nil, fmt.Errorf("line %v: empty profile name", line)
+			}
+			f[name] = f[name] // profile can be empty
+			continue
+		}
+		if !strings.Contains(s, "=") {
+			return nil, fmt.Errorf("line %v: expecting KEY=value: %q", line, s)
+		}
+		f[name] = append(f[name], s)
+	}
+	return f, sc.Err()
+}
+
+//----------
+
+// Allows the ".editorenv" file with its current content. Changing the content requires allowing it again.
+func (ep *EnvProfiles) AllowFile(filename string) error {
+	if filepath.Base(filename) != EnvProfilesFilename {
+		return fmt.Errorf("not an env file: %v", filename)
+	}
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	if _, err := parseEnvFile(b); err != nil {
+		return fmt.Errorf("%v: %v", filename, err)
+	}
+
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+	m := ep.allowed()
+	m[filename] = envFileHash(b)
+	buf := &bytes.Buffer{}
+	for k, v := range m {
+		fmt.Fprintf(buf, "%v %v\n", v, k)
+	}
+	return osutil.WriteFileAtomic(ep.allowedFilename, buf.Bytes(), 0600, false)
+}
+
+// Filename -> content hash. Format: one "<hash> <filename>" per line.
+func (ep *EnvProfiles) allowed() map[string]string {
+	m := map[string]string{}
+	b, err := ioutil.ReadFile(ep.allowedFilename)
+	if err != nil {
+		return m
+	}
+	for _, l := range strings.Split(string(b), "\n") {
+		u := strings.SplitN(l, " ", 2)
+		if len(u) == 2 {
+			m[u[1]] = u[0]
+		}
+	}
+	return m
+}
+
+func envFileHash(b []byte) string {
+	return hex.EncodeToString(bytesHash(b))
+}
+
+//----------
+
+// Values can refer to the previous env (ex: PATH=$PATH:/a/b).
+func setEnvsExpand(env []string, vars []string) []string {
+	for _, s := range vars {
+		u := strings.SplitN(s, "=", 2)
+		if len(u) != 2 {
+			continue
+		}
+		v := os.Expand(u[1], func(k string) string {
+			return osutil.GetEnv(env, k)
+		})
+		env = osutil.SetEnv(env, u[0], v)
+	}
+	return env
+}
@@ -0,0 +1,115 @@
+package core
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jmigpin/editor/util/osutil"
+)
+
+func TestEnvProfiles1(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "editor_envprofiles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	dir := filepath.Join(tmpDir, "a", "b")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	src1 := "V1=a\nV2=a\n[arm]\nGOARCH=arm\nV3=$V1-x\n"
+	src2 := "# comment\nV2=b\n[arm]\nGOARCH=arm64\n"
+	if err := ioutil.WriteFile(filepath.Join(tmpDir, "a", EnvProfilesFilename), []byte(src1), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, EnvProfilesFilename), []byte(src2), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ep := NewEnvProfiles()
+	ep.allowedFilename = filepath.Join(tmpDir, "allowed")
+	ep.ParseToolbarVars([]string{`Exit | $env_tb="V4=c V5=d"`})
+
+	// not allowed
+	env, err := ep.Environ(dir, "")
+	if err == nil || osutil.GetEnv(env, "V1") != "" {
+		t.Fatal("expecting not allowed")
+	}
+	for _, d := range []string{filepath.Dir(dir), dir} {
+		if err := ep.AllowFile(filepath.Join(d, EnvProfilesFilename)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	env, err = ep.Environ(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if osutil.GetEnv(env, "V1") != "a" || osutil.GetEnv(env, "V2") != "b" || osutil.GetEnv(env, "GOARCH") == "arm" {
+		t.Fatal(env)
+	}
+
+	// nearest profile
+	env, err = ep.Environ(dir, "arm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if osutil.GetEnv(env, "GOARCH") != "arm64" || osutil.GetEnv(env, "V3") != "" {
+		t.Fatal(env)
+	}
+	env, err = ep.Environ(filepath.Dir(dir), "arm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if osutil.GetEnv(env, "GOARCH") != "arm" || osutil.GetEnv(env, "V3") != "a-x" {
+		t.Fatal(env)
+	}
+
+	// root toolbar profile, and default
+	ep.ParseToolbarVars([]string{`$env=tb | $env_tb="V4=c V5=d"`})
+	env, err = ep.Environ(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if osutil.GetEnv(env, "V4") != "c" || osutil.GetEnv(env, "V5") != "d" {
+		t.Fatal(env)
+	}
+
+	if _, err := ep.Environ(dir, "nothere"); err == nil {
+		t.Fatal("expecting error")
+	}
+
+	// row profile of a file
+	filename := filepath.Join(dir, "a.go")
+	ep.SetFileProfile(filename, "arm")
+	env, err = ep.FileEnviron(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if osutil.GetEnv(env, "GOARCH") != "arm64" {
+		t.Fatal(env)
+	}
+
+	// remote file: no env files, row profile from the root toolbar
+	filename2 := "ssh://host" + filename
+	ep.SetFileProfile(filename2, "tb")
+	env, err = ep.FileEnviron(filename2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if osutil.GetEnv(env, "V2") != "" || osutil.GetEnv(env, "V4") != "c" {
+		t.Fatal(env)
+	}
+
+	// changed content needs to be allowed again
+	if err := ioutil.WriteFile(filepath.Join(dir, EnvProfilesFilename), []byte("V2=c\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	env, err = ep.Environ(dir, "")
+	if err == nil || osutil.GetEnv(env, "V2") != "a" {
+		t.Fatal("expecting not allowed", osutil.GetEnv(env, "V2"))
+	}
+}
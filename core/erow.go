@@ -4,11 +4,16 @@ import (
 	"context"
 	"errors"
 	"io"
+	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync/atomic"
 
+	"github.com/jmigpin/editor/core/fsys"
 	"github.com/jmigpin/editor/core/toolbarparser"
 	"github.com/jmigpin/editor/ui"
+	"github.com/jmigpin/editor/util/encodingutil"
 	"github.com/jmigpin/editor/util/iout"
 	"github.com/jmigpin/editor/util/uiutil/event"
 )
@@ -16,16 +21,29 @@ import (
 //----------
 
 type ERow struct {
+	Id     int // unique (ex: control socket row paths)
 	Ed     *Editor
 	Row    *ui.Row
 	Info   *ERowInfo
 	Exec   *ERowExec
+	Watch  *ERowWatch
 	TbData toolbarparser.Data
 
 	highlightDuplicates           bool
 	disableTextAreaSetStrCallback bool
 
 	termFilter bool
+	pty        bool
+	stdin      bool
+	watchVar   bool
+	watchGlob  string
+	maxLines   int    // $maxlines (zero: no limit)
+	maxBytes   int    // $maxbytes (zero: no limit)
+	tee        string // $tee filename
+	envName    string // $env profile
+	autoReload int    // $autoreload (1: on, -1: off, 0: editor option)
+
+	listDir *listDirState // last ListDir options (dir rows)
 
 	ctx       context.Context // erow general context
 	ctxCancel context.CancelFunc
@@ -40,11 +58,14 @@ func NewERow(ed *Editor, info *ERowInfo, rowPos *ui.RowPos) *ERow {
 	row := rowPos.Column.NewRowBefore(rowPos.NextRow)
 
 	erow := &ERow{Ed: ed, Row: row, Info: info}
+	erow.Id = newERowId()
 	erow.Exec = NewERowExec(erow)
+	erow.Watch = NewERowWatch(erow)
 
 	// TODO: join with updateToolbarPart0
 	s2 := ed.HomeVars.Encode(erow.Info.Name())
 	erow.Row.Toolbar.SetStrClearHistory(s2)
+	erow.updateToolbarFormat()
 
 	erow.initHandlers()
 	erow.parseToolbar() // after handlers are set
@@ -60,6 +81,12 @@ func NewERow(ed *Editor, info *ERowInfo, rowPos *ui.RowPos) *ERow {
 	return erow
 }
 
+var erowIds int32
+
+func newERowId() int {
+	return int(atomic.AddInt32(&erowIds, 1))
+}
+
 //----------
 
 func (erow *ERow) initHandlers() {
@@ -76,8 +103,10 @@ func (erow *ERow) initHandlers() {
 	erow.Info.UpdateFsDifferRowState()
 
 	// register with watcher
-	if !erow.Info.IsSpecial() && len(erow.Info.ERows) == 1 {
-		erow.Ed.Watcher.Add(erow.Info.Name())
+	if !erow.Info.IsSpecial() && fsys.IsLocal(erow.Info.Name()) && len(erow.Info.ERows) == 1 {
+		if err := erow.Ed.Watcher.Add(erow.Info.Name()); err != nil {
+			erow.Ed.Errorf("watch: %v", err)
+		}
 	}
 
 	// toolbar set str
@@ -101,6 +130,8 @@ func (erow *ERow) initHandlers() {
 	// textarea edit
 	row.TextArea.EvReg.Add(ui.TextAreaWriteOpEventId, func(ev0 interface{}) {
 		ev := ev0.(*ui.TextAreaWriteOpEvent)
+		// input for the executing process
+		erow.Exec.onWriteOp(ev.WriteOp)
 		// update duplicate edits to keep offset/cursor in position
 		if erow.Info.IsFileButNotDir() {
 			for _, e := range erow.Info.ERows {
@@ -170,12 +201,14 @@ func (erow *ERow) initHandlers() {
 		erow.ctxCancel()
 
 		// ensure execution (if any) is stopped
+		erow.Watch.Stop()
 		erow.Exec.Stop()
 
 		// unregister from editor
 		erow.Info.RemoveERow(erow)
 		if len(erow.Info.ERows) == 0 {
 			erow.Ed.DeleteERowInfo(erow.Info.Name())
+			erow.Ed.EnvProfiles.SetFileProfile(erow.Info.Name(), "")
 		}
 
 		// update row state
@@ -183,7 +216,7 @@ func (erow *ERow) initHandlers() {
 		erow.Info.UpdateDuplicateHighlightRowState()
 
 		// unregister with watcher
-		if !erow.Info.IsSpecial() && len(erow.Info.ERows) == 0 {
+		if !erow.Info.IsSpecial() && fsys.IsLocal(erow.Info.Name()) && len(erow.Info.ERows) == 0 {
 			erow.Ed.Watcher.Remove(erow.Info.Name())
 		}
 
@@ -247,6 +280,78 @@ func (erow *ERow) parseToolbarVars() {
 			erow.termFilter = true
 		}
 	}
+
+	// $pty
+	erow.pty = false
+	if v, ok := vmap["$pty"]; ok {
+		if v == "" || strings.ToLower(v) == "true" {
+			erow.pty = true
+		}
+	}
+
+	// $stdin
+	erow.stdin = false
+	if v, ok := vmap["$stdin"]; ok {
+		if v == "" || strings.ToLower(v) == "true" {
+			erow.stdin = true
+		}
+	}
+
+	// $watch
+	erow.watchGlob, erow.watchVar = vmap["$watch"]
+	if erow.Watch.on {
+		if !erow.watchVar && erow.Watch.byVar {
+			erow.Watch.Stop()
+		} else {
+			erow.Watch.glob = erow.watchGlob
+		}
+	}
+
+	// $maxlines, $maxbytes
+	erow.maxLines = erow.parseToolbarIntVar(vmap, "$maxlines")
+	erow.maxBytes = erow.parseToolbarIntVar(vmap, "$maxbytes")
+
+	// $tee
+	erow.tee = vmap["$tee"]
+
+	// $env
+	erow.envName = vmap["$env"]
+	if !erow.Info.IsDir() {
+		erow.Ed.EnvProfiles.SetFileProfile(erow.Info.Name(), erow.envName)
+	}
+
+	// $autoreload
+	erow.autoReload = 0
+	if v, ok := vmap["$autoreload"]; ok {
+		switch strings.ToLower(v) {
+		case "", "true":
+			erow.autoReload = 1
+		case "false":
+			erow.autoReload = -1
+		default:
+			erow.Ed.Errorf("$autoreload: expecting true/false: %q", v)
+		}
+	}
+}
+
+func (erow *ERow) autoReloadOn() bool {
+	if erow.autoReload != 0 {
+		return erow.autoReload > 0
+	}
+	return erow.Ed.AutoReload
+}
+
+func (erow *ERow) parseToolbarIntVar(vmap toolbarparser.VarMap, name string) int {
+	v, ok := vmap[name]
+	if !ok || v == "" {
+		return 0
+	}
+	u, err := strconv.Atoi(v)
+	if err != nil || u < 0 {
+		erow.Ed.Errorf("%v: expecting positive integer: %q", name, v)
+		return 0
+	}
+	return u
 }
 
 func (erow *ERow) setVarFontTheme(s string) error {
@@ -276,6 +381,37 @@ func (erow *ERow) updateToolbarPart0() {
 	}
 }
 
+// Shows the file format in the toolbar if not the default (ex: "SetEncoding utf16le | SetLineEnding crlf"), or updates the parts already present.
+func (erow *ERow) updateToolbarFormat() {
+	if !erow.Info.IsFileButNotDir() {
+		return
+	}
+	f := erow.Info.Format()
+	str := erow.Row.Toolbar.Str()
+	str2 := setToolbarCmdPart(str, "SetEncoding", f.Encoding.String(), f.Encoding == encodingutil.UTF8)
+	str2 = setToolbarCmdPart(str2, "SetLineEnding", f.LineEnding.String(), f.LineEnding == encodingutil.LF)
+	if str2 != str {
+		erow.Row.Toolbar.SetStrClearHistory(str2)
+	}
+}
+
+// Replaces the args of the cmd part. If not present, the part is inserted after the first part, unless optional.
+func setToolbarCmdPart(str, cmd, arg string, optional bool) string {
+	data := toolbarparser.Parse(str)
+	for _, p := range data.Parts {
+		if len(p.Args) >= 1 && p.Args[0].Str() == cmd {
+			a0, an := p.Args[0], p.Args[len(p.Args)-1]
+			return str[:a0.End] + " " + arg + str[an.End:]
+		}
+	}
+	if optional || len(data.Parts) == 0 || len(data.Parts[0].Args) == 0 {
+		return str
+	}
+	args := data.Parts[0].Args
+	k := args[len(args)-1].End
+	return str[:k] + " | " + cmd + " " + arg + str[k:]
+}
+
 //----------
 
 func (erow *ERow) Reload() {
@@ -305,6 +441,7 @@ func (erow *ERow) ToolbarSetStrAfterNameClearHistory(s string) {
 	i := arg.End
 	str := erow.Row.Toolbar.Str()[:i] + s
 	erow.Row.Toolbar.SetStrClearHistory(str)
+	erow.updateToolbarFormat()
 }
 
 //----------
@@ -312,7 +449,7 @@ func (erow *ERow) ToolbarSetStrAfterNameClearHistory(s string) {
 func (erow *ERow) TextAreaAppendBytesAsync(p []byte) <-chan struct{} {
 	comm := make(chan struct{})
 	erow.Ed.UI.RunOnUIGoRoutine(func() {
-		erow.TextAreaAppendBytes(p)
+		erow.Exec.appendOutput(p)
 		close(comm)
 	})
 	return comm
@@ -332,10 +469,25 @@ func (erow *ERow) TextAreaWriter() io.WriteCloser {
 	// terminal filter (escape sequences) (before goroutine to avoid data race)
 	termFilter := erow.termFilter && erow.Info.IsDir()
 
+	// mirror output to a file
+	var tee io.WriteCloser
+	if erow.tee != "" {
+		f, err := erow.openTeeFile()
+		if err != nil {
+			erow.Ed.Error(err)
+		} else {
+			tee = f
+		}
+	}
+
 	prc, pwc := io.Pipe()
 	go func() {
 		var rc io.ReadCloser = prc
 
+		if tee != nil {
+			defer tee.Close()
+			rc = &teeReadCloser{ReadCloser: rc, w: tee, erow: erow}
+		}
 		if termFilter {
 			rc = NewTerminalFilter(erow, rc)
 		}
@@ -368,6 +520,77 @@ func (erow *ERow) readLoopToTextArea(rd io.Reader) {
 	}
 }
 
+// Environment for the cmds that run in the row directory (env profiles applied). Errors are reported and the process environment is used.
+func (erow *ERow) Environ() []string {
+	env, err := erow.Ed.EnvProfiles.Environ(erow.Info.Dir(), erow.envName)
+	if err != nil {
+		erow.Ed.Error(err)
+	}
+	return env
+}
+
+//----------
+
+// Output file is relative to the row directory, and is appended to.
+func (erow *ERow) openTeeFile() (*os.File, error) {
+	filename := erow.teeFilename()
+	flags := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	return os.OpenFile(filename, flags, 0644)
+}
+
+// Empty if $tee is not set.
+func (erow *ERow) teeFilename() string {
+	if erow.tee == "" {
+		return ""
+	}
+	filename := erow.Ed.HomeVars.Decode(erow.tee)
+	if !filepath.IsAbs(filename) {
+		filename = filepath.Join(erow.Info.Dir(), filename)
+	}
+	return filename
+}
+
+// Writes what is read to w. Stops writing on the first write error (reported) but continues reading.
+type teeReadCloser struct {
+	io.ReadCloser
+	w    io.Writer
+	erow *ERow
+}
+
+func (t *teeReadCloser) Read(p []byte) (int, error) {
+	n, err := t.ReadCloser.Read(p)
+	if n > 0 && t.w != nil {
+		if _, err2 := t.w.Write(p[:n]); err2 != nil {
+			t.erow.Ed.Errorf("tee: %v", err2)
+			t.w = nil
+		}
+	}
+	return n, err
+}
+
+//----------
+
+// Visible columns/lines of the textarea (estimated with the width of a monospaced rune).
+func (erow *ERow) textAreaSizeInRunes() (int, int) {
+	ta := erow.Row.TextArea
+	size := ta.Bounds.Size()
+	cols, rows := 0, 0
+	if adv, ok := ta.Drawer.Face().GlyphAdvance('M'); ok && adv > 0 {
+		cols = size.X / adv.Ceil()
+	}
+	if lh := ta.LineHeight(); lh > 0 {
+		rows = size.Y / lh
+	}
+	// small or hidden rows
+	if cols < 20 {
+		cols = 80
+	}
+	if rows < 5 {
+		rows = 24
+	}
+	return cols, rows
+}
+
 //----------
 
 func (erow *ERow) Flash() {
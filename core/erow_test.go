@@ -0,0 +1,23 @@
+package core
+
+import "testing"
+
+func TestSetToolbarCmdPart(t *testing.T) {
+	type test struct {
+		in, out  string
+		optional bool
+	}
+	tests := []test{
+		{"/a/b.txt | Save", "/a/b.txt | SetEncoding latin1 | Save", false},
+		{"/a/b.txt", "/a/b.txt | SetEncoding latin1", false},
+		{"/a/b.txt | Save", "/a/b.txt | Save", true},
+		{"/a/b.txt | SetEncoding utf8 | Save", "/a/b.txt | SetEncoding latin1 | Save", true},
+		{"/a/b.txt | SetEncoding | Save", "/a/b.txt | SetEncoding latin1 | Save", false},
+	}
+	for _, tt := range tests {
+		s := setToolbarCmdPart(tt.in, "SetEncoding", "latin1", tt.optional)
+		if s != tt.out {
+			t.Fatalf("%q -> %q", tt.in, s)
+		}
+	}
+}
@@ -1,12 +1,21 @@
 package core
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
+	"os/exec"
 	"sync"
+	"time"
+	"unicode/utf8"
 
 	"github.com/jmigpin/editor/ui"
+	"github.com/jmigpin/editor/util/drawutil/drawer4"
+	"github.com/jmigpin/editor/util/iout/iorw"
+	"github.com/jmigpin/editor/util/mathutil"
+	"github.com/jmigpin/editor/util/termutil"
+	"github.com/jmigpin/editor/util/uiutil/widget"
 )
 
 type ERowExec struct {
@@ -16,11 +25,41 @@ type ERowExec struct {
 		ctx    context.Context
 		cancel context.CancelFunc
 		w      io.WriteCloser
+		stdin  chan []byte // nil if not accepting input
+		job    *Job
 	}
+
+	// text typed after the output end is sent to stdin (ui goroutine only)
+	input struct {
+		on            bool
+		outputEnd     int
+		writingOutput bool
+	}
+
+	// terminal emulator output (ui goroutine only)
+	term struct {
+		on          bool
+		screenStart int // screen end is the output end
+		ops         []*drawer4.ColorizeOp
+	}
+
+	// output limits: $maxlines, $maxbytes (ui goroutine only)
+	limit struct {
+		lines int // newlines in the textarea, -1 if unknown
+	}
+
+	// end time of the last cmd run (ui goroutine only)
+	ended time.Time
+
+	// last cmd run in the row (ui goroutine only)
+	rerun     func()
+	rerunArgs []string // saved in sessions, nil if it can't be run again from the args
 }
 
 func NewERowExec(erow *ERow) *ERowExec {
-	return &ERowExec{erow: erow}
+	eexec := &ERowExec{erow: erow}
+	eexec.limit.lines = -1
+	return eexec
 }
 
 //----------
@@ -37,12 +76,23 @@ func (eexec *ERowExec) Start(fexec func(context.Context, io.Writer) error) {
 	// indicate the row is running
 	eexec.erow.Ed.UI.RunOnUIGoRoutine(func() {
 		eexec.erow.Row.SetState(ui.RowStateExecuting, true)
+		eexec.erow.Row.SetState(ui.RowStateExecSuccess, false)
+		eexec.erow.Row.SetState(ui.RowStateExecFailure, false)
+		eexec.setTermOps(nil)
 	})
 
 	// new context
 	ctx, cancel := context.WithCancel(eexec.erow.ctx)
 	eexec.mu.ctx, eexec.mu.cancel = ctx, cancel
 
+	// job info (the cmd is set later by the running func)
+	eexec.mu.job = &Job{
+		Id:    newJobId(),
+		ERow:  eexec.erow,
+		Start: time.Now(),
+		Dir:   eexec.erow.Info.Dir(),
+	}
+
 	// writer
 	w := eexec.erow.TextAreaWriter() // needs to be closed in the end
 	eexec.mu.w = w                   // keep w to ensure early close on clear
@@ -69,9 +119,14 @@ func (eexec *ERowExec) clear() {
 	eexec.mu.cancel = nil
 	eexec.mu.w.Close()
 	eexec.mu.w = nil
+	eexec.mu.job = nil
+	eexec.closeStdin()
 
 	// indicate the row is not running
 	eexec.erow.Ed.UI.RunOnUIGoRoutine(func() {
+		eexec.input.on = false
+		eexec.term.on = false
+		eexec.ended = time.Now()
 		eexec.erow.Row.SetState(ui.RowStateExecuting, false)
 	})
 }
@@ -85,3 +140,415 @@ func (eexec *ERowExec) Stop() {
 		eexec.mu.cancel()
 	}
 }
+
+//----------
+
+// Sets the job cmd if the execution was not replaced. The exec cmd can be nil (ex: godebug session).
+func (eexec *ERowExec) setJobCmd(ctx context.Context, cmd *exec.Cmd, cmdStr string) {
+	eexec.mu.Lock()
+	defer eexec.mu.Unlock()
+	if eexec.mu.ctx != ctx || eexec.mu.job == nil {
+		return
+	}
+	eexec.mu.job.Cmd = cmdStr
+	eexec.mu.job.cmd = cmd
+}
+
+// Returns a copy of the current job, if executing.
+func (eexec *ERowExec) Job() (Job, bool) {
+	eexec.mu.Lock()
+	defer eexec.mu.Unlock()
+	if eexec.mu.job == nil {
+		return Job{}, false
+	}
+	return *eexec.mu.job, true
+}
+
+//----------
+
+// Shows the exit status in the row square until the next execution. Ignored if the execution was replaced.
+func (eexec *ERowExec) setExitStatus(ctx context.Context, success bool) {
+	eexec.mu.Lock()
+	defer eexec.mu.Unlock()
+	if eexec.mu.ctx != ctx {
+		return
+	}
+	eexec.erow.Ed.UI.RunOnUIGoRoutine(func() {
+		eexec.erow.Row.SetState(ui.RowStateExecSuccess, success)
+		eexec.erow.Row.SetState(ui.RowStateExecFailure, !success)
+	})
+}
+
+//----------
+
+// Keeps the last cmd to be able to run it again (Rerun, sessions).
+func (eexec *ERowExec) setRerun(args []string, fn func()) {
+	eexec.rerunArgs = args
+	eexec.rerun = fn
+}
+
+// Runs the last external cmd again with the same args and env.
+func (eexec *ERowExec) Rerun() error {
+	if eexec.rerun == nil {
+		return fmt.Errorf("no cmd to rerun")
+	}
+	eexec.rerun()
+	return nil
+}
+
+//----------
+
+// Text typed after the output end will be sent to the writer (line by line). Closed on SendEOF or when the execution ends.
+func (eexec *ERowExec) SetStdin(ctx context.Context, wc io.WriteCloser) {
+	eexec.mu.Lock()
+	defer eexec.mu.Unlock()
+
+	// execution already ended or was replaced
+	if eexec.mu.ctx != ctx || eexec.mu.cancel == nil {
+		wc.Close()
+		return
+	}
+
+	// writes in a goroutine to not block the ui if the process is not reading
+	ch := make(chan []byte, 64)
+	eexec.mu.stdin = ch
+	go func() {
+		defer wc.Close()
+		for b := range ch {
+			if _, err := wc.Write(b); err != nil {
+				break
+			}
+		}
+		for range ch { // drain
+		}
+	}()
+
+	eexec.erow.Ed.UI.RunOnUIGoRoutine(func() {
+		// current content is all output
+		eexec.input.on = true
+		eexec.input.outputEnd = eexec.erow.Row.TextArea.Len()
+	})
+}
+
+// Sends the pending input (if any) and closes stdin.
+func (eexec *ERowExec) SendEOF() error {
+	if !eexec.input.on {
+		return fmt.Errorf("not accepting input")
+	}
+	eexec.sendInput(true)
+	eexec.input.on = false
+
+	eexec.mu.Lock()
+	defer eexec.mu.Unlock()
+	eexec.closeStdin()
+	return nil
+}
+
+// Needs mu lock.
+func (eexec *ERowExec) closeStdin() {
+	if eexec.mu.stdin != nil {
+		close(eexec.mu.stdin)
+		eexec.mu.stdin = nil
+	}
+}
+
+//----------
+
+// Inserts the process output before the pending input, and trims the output start if there are limits. Called from the ui goroutine.
+func (eexec *ERowExec) appendOutput(p []byte) {
+	followEnd := eexec.limitFollowEnd()
+	lines := eexec.limit.lines
+	eexec.appendOutput2(p)
+	if lines >= 0 {
+		eexec.limit.lines = lines + bytes.Count(p, []byte("\n"))
+	}
+	eexec.limitOutput(followEnd)
+}
+
+func (eexec *ERowExec) appendOutput2(p []byte) {
+	erow := eexec.erow
+	if !eexec.input.on {
+		erow.TextAreaAppendBytes(p)
+		return
+	}
+
+	ta := erow.Row.TextArea
+	i := eexec.input.outputEnd
+	if i > ta.Len() {
+		i = ta.Len()
+	}
+	eexec.input.writingOutput = true
+	err := ta.InsertBytesKeepHistory(i, p)
+	eexec.input.writingOutput = false
+	if err != nil {
+		erow.Ed.Error(err)
+		return
+	}
+	eexec.input.outputEnd = i + len(p)
+
+	// keep cursor after the pending input
+	ta.UpdateWriteOp(&widget.RWWriteOpCb{Type: iorw.InsertWOp, Index: i, Length1: len(p)})
+}
+
+// Keeps track of the output positions on edits, and sends complete input lines. Called from the ui goroutine.
+func (eexec *ERowExec) onWriteOp(u *widget.RWWriteOpCb) {
+	if eexec.input.writingOutput {
+		return
+	}
+	eexec.limit.lines = -1
+	if eexec.term.on {
+		eexec.term.screenStart = writeOpShiftIndex(u, eexec.term.screenStart)
+	}
+	if len(eexec.term.ops) > 0 {
+		eexec.setTermOps(writeOpShiftColorizeOps(u, eexec.term.ops))
+	}
+	if eexec.input.on {
+		eexec.input.outputEnd = writeOpShiftIndex(u, eexec.input.outputEnd)
+		eexec.sendInput(false)
+	}
+}
+
+// Sends the input after the output end up to the last newline (all if eof).
+func (eexec *ERowExec) sendInput(eof bool) {
+	ta := eexec.erow.Row.TextArea
+	i := eexec.input.outputEnd
+	n := ta.Len() - i
+	if n <= 0 {
+		return
+	}
+	b, err := ta.TextCursor.RW().ReadNCopyAt(i, n)
+	if err != nil {
+		eexec.erow.Ed.Error(err)
+		return
+	}
+	if !eof {
+		k := bytes.LastIndexByte(b, '\n')
+		if k < 0 {
+			return // no complete line yet
+		}
+		b = b[:k+1]
+	}
+
+	eexec.mu.Lock()
+	defer eexec.mu.Unlock()
+	if eexec.mu.stdin == nil {
+		return
+	}
+	select {
+	case eexec.mu.stdin <- b:
+		// sent input becomes part of the output
+		eexec.input.outputEnd += len(b)
+	default:
+		eexec.erow.Ed.Errorf("stdin: process is not reading input")
+	}
+}
+
+//----------
+
+// Output will be handled by termUpdate.
+func (eexec *ERowExec) startTerm() {
+	eexec.erow.Ed.UI.RunOnUIGoRoutine(func() {
+		eexec.term.on = true
+		eexec.term.screenStart = eexec.erow.Row.TextArea.Len()
+	})
+}
+
+func (eexec *ERowExec) termUpdateAsync(upd *termutil.Update) <-chan struct{} {
+	comm := make(chan struct{})
+	eexec.erow.Ed.UI.RunOnUIGoRoutine(func() {
+		eexec.termUpdate(upd)
+		close(comm)
+	})
+	return comm
+}
+
+// Replaces the previous screen text with the commited text and the new screen. Called from the ui goroutine.
+func (eexec *ERowExec) termUpdate(upd *termutil.Update) {
+	if !eexec.term.on {
+		return
+	}
+	ta := eexec.erow.Row.TextArea
+	s := mathutil.Smallest(eexec.term.screenStart, ta.Len())
+	e := ta.Len()
+	if eexec.input.on {
+		e = mathutil.Smallest(eexec.input.outputEnd, e)
+	}
+	followEnd := eexec.limitFollowEnd()
+	defer eexec.limitOutput(followEnd)
+	eexec.limit.lines = -1
+
+	ops := eexec.term.ops
+	if upd.Clear {
+		s = 0
+		ops = nil
+	}
+
+	b := append(append([]byte{}, upd.Commit...), upd.Screen...)
+	eexec.input.writingOutput = true
+	err := ta.OverwriteBytesClearHistory(s, e-s, b)
+	eexec.input.writingOutput = false
+	if err != nil {
+		eexec.erow.Ed.Error(err)
+		return
+	}
+	ta.UpdateWriteOp(&widget.RWWriteOpCb{Type: iorw.OverwriteWOp, Index: s, Length1: e - s, Length2: len(b)})
+
+	// colorize ops: keep ops before the old screen
+	k := len(ops)
+	for k > 0 && ops[k-1].Offset >= s {
+		k--
+	}
+	ops = ops[:k]
+	ops = appendTermColorizeOps(ops, upd.CommitOps, s)
+	ops = appendTermColorizeOps(ops, upd.ScreenOps, s+len(upd.Commit))
+	eexec.setTermOps(ops)
+
+	eexec.term.screenStart = s + len(upd.Commit)
+	eexec.input.outputEnd = s + len(b)
+}
+
+func (eexec *ERowExec) setTermOps(ops []*drawer4.ColorizeOp) {
+	eexec.term.ops = ops
+	eexec.erow.Row.TextArea.SetCustomColorizeOps(ops)
+}
+
+func appendTermColorizeOps(ops []*drawer4.ColorizeOp, tops []*termutil.Op, offset int) []*drawer4.ColorizeOp {
+	for _, op := range tops {
+		op2 := &drawer4.ColorizeOp{
+			Offset: offset + op.Offset,
+			Fg:     op.Style.Fg,
+			Bg:     op.Style.Bg,
+		}
+		ops = append(ops, op2)
+	}
+	return ops
+}
+
+//----------
+
+func (eexec *ERowExec) limitOn() bool {
+	return eexec.erow.maxLines > 0 || eexec.erow.maxBytes > 0
+}
+
+// Returns true if the output end is visible (the view should stay at the end after the output is limited).
+func (eexec *ERowExec) limitFollowEnd() bool {
+	if !eexec.limitOn() {
+		return false
+	}
+	ta := eexec.erow.Row.TextArea
+	return ta.IndexVisible(ta.Len())
+}
+
+// Removes the start of the textarea to keep it within $maxlines/$maxbytes. Called from the ui goroutine.
+func (eexec *ERowExec) limitOutput(followEnd bool) {
+	if !eexec.limitOn() {
+		return
+	}
+	erow := eexec.erow
+	ta := erow.Row.TextArea
+	rw := ta.TextCursor.RW()
+	b, err := rw.ReadNSliceAt(rw.Min(), iorw.MMLen(rw))
+	if err != nil {
+		erow.Ed.Error(err)
+		return
+	}
+
+	n := 0
+	if erow.maxLines > 0 {
+		if eexec.limit.lines < 0 {
+			eexec.limit.lines = bytes.Count(b, []byte("\n"))
+		}
+		n = limitLinesIndex(b, eexec.limit.lines-erow.maxLines)
+	}
+	if erow.maxBytes > 0 && len(b)-n > erow.maxBytes {
+		n = limitBytesIndex(b, len(b)-erow.maxBytes)
+	}
+	if n > 0 {
+		lines := eexec.limit.lines
+		if lines >= 0 {
+			lines -= bytes.Count(b[:n], []byte("\n"))
+		}
+
+		// not writingOutput: the output positions need to be updated
+		if err := ta.OverwriteBytesClearHistory(rw.Min(), n, nil); err != nil {
+			erow.Ed.Error(err)
+			return
+		}
+		ta.UpdateWriteOp(&widget.RWWriteOpCb{Type: iorw.DeleteWOp, Index: rw.Min(), Length1: n})
+
+		eexec.limit.lines = lines // the write op callback sets it as unknown
+	}
+
+	if followEnd && !ta.IndexVisible(ta.Len()) {
+		ta.MakeIndexVisible(ta.Len())
+	}
+}
+
+// Index after the first k lines.
+func limitLinesIndex(b []byte, k int) int {
+	i := 0
+	for ; k > 0; k-- {
+		j := bytes.IndexByte(b[i:], '\n')
+		if j < 0 {
+			break
+		}
+		i += j + 1
+	}
+	return i
+}
+
+// Index of the line start at or after i, or the rune start if there is no newline.
+func limitBytesIndex(b []byte, i int) int {
+	if j := bytes.IndexByte(b[i:], '\n'); j >= 0 {
+		return i + j + 1
+	}
+	for i < len(b) && !utf8.RuneStart(b[i]) {
+		i++
+	}
+	return i
+}
+
+//----------
+
+// Index position after the write op.
+func writeOpShiftIndex(u *widget.RWWriteOpCb, i int) int {
+	if u.Index >= i {
+		return i
+	}
+	switch u.Type {
+	case iorw.InsertWOp:
+		i += u.Length1
+	case iorw.DeleteWOp:
+		i -= mathutil.Smallest(u.Length1, i-u.Index)
+	case iorw.OverwriteWOp:
+		i -= mathutil.Smallest(u.Length1, i-u.Index)
+		i += u.Length2
+	}
+	return i
+}
+
+// Ops inside a deleted range are removed.
+func writeOpShiftColorizeOps(u *widget.RWWriteOpCb, ops []*drawer4.ColorizeOp) []*drawer4.ColorizeOp {
+	s, e := u.Index, u.Index
+	delta := u.Length1
+	switch u.Type {
+	case iorw.DeleteWOp:
+		e = s + u.Length1
+		delta = -u.Length1
+	case iorw.OverwriteWOp:
+		e = s + u.Length1
+		delta = u.Length2 - u.Length1
+	}
+	ops2 := ops[:0]
+	for _, op := range ops {
+		switch {
+		case op.Offset < s:
+		case op.Offset < e:
+			continue // removed
+		default:
+			op.Offset += delta
+		}
+		ops2 = append(ops2, op)
+	}
+	return ops2
+}
@@ -0,0 +1,30 @@
+package core
+
+import (
+	"testing"
+)
+
+func TestLimitIndex1(t *testing.T) {
+	b := []byte("a\nbb\nccc\nd")
+	for _, u := range []struct{ k, i int }{
+		{0, 0}, {1, 2}, {2, 5}, {3, 9}, {4, 9},
+	} {
+		if i := limitLinesIndex(b, u.k); i != u.i {
+			t.Fatalf("lines %v: %v != %v", u.k, i, u.i)
+		}
+	}
+
+	for _, u := range []struct{ k, i int }{
+		{0, 2}, {2, 5}, {3, 5}, {9, 9},
+	} {
+		if i := limitBytesIndex(b, u.k); i != u.i {
+			t.Fatalf("bytes %v: %v != %v", u.k, i, u.i)
+		}
+	}
+
+	// no newline: rune start
+	b2 := []byte("aé")
+	if i := limitBytesIndex(b2, 2); i != 3 {
+		t.Fatal(i)
+	}
+}
@@ -5,12 +5,14 @@ import (
 	"context"
 	"crypto/sha1"
 	"fmt"
-	"io/ioutil"
 	"os"
 	"path/filepath"
 	"time"
 
+	"github.com/jmigpin/editor/core/fsys"
 	"github.com/jmigpin/editor/ui"
+	"github.com/jmigpin/editor/util/diffutil"
+	"github.com/jmigpin/editor/util/encodingutil"
 	"github.com/jmigpin/editor/util/osutil"
 )
 
@@ -29,8 +31,9 @@ type ERowInfo struct {
 
 	// savedHash keeps the hash known even if the file gets deleted and reappears later
 	savedHash struct {
-		size int
-		hash []byte
+		size    int
+		hash    []byte
+		content []byte // base for merges
 	}
 
 	// filesystem hash (reflects changes by other programs)
@@ -46,10 +49,15 @@ type ERowInfo struct {
 		size    int
 		hash    []byte
 	}
+
+	// file encoding and line endings: the content is edited as utf8 with "\n" line endings
+	format      encodingutil.Format // used on save
+	fsFormat    encodingutil.Format // detected on read
+	fsFormatErr error               // warning from the last read (ex: unknown encoding)
 }
 
 func ReadERowInfo(ed *Editor, name string) *ERowInfo {
-	name = osutil.FilepathClean(name)
+	name = fsys.Clean(name)
 
 	// try to update the instance already used
 	info, ok := ed.ERowInfo(name)
@@ -73,7 +81,7 @@ func (info *ERowInfo) readFileInfo() {
 		info.UpdateExistsRowState()
 	}()
 
-	fi, err := os.Stat(info.name)
+	fi, err := fsys.Stat(info.name)
 	if err != nil {
 		// keep old info.fi to allow file/dir detection
 		info.fiErr = err
@@ -134,7 +142,7 @@ func (info *ERowInfo) Dir() string {
 	if info.IsDir() {
 		return info.Name()
 	}
-	return filepath.Dir(info.Name())
+	return fsys.Dir(info.Name())
 }
 
 //----------
@@ -167,9 +175,10 @@ func (info *ERowInfo) setEditedHash(hash []byte, size int) {
 	info.editedHash.updated = true
 }
 
-func (info *ERowInfo) setSavedHash(hash []byte, size int) {
-	info.savedHash.size = size
+func (info *ERowInfo) setSavedHash(hash []byte, content []byte) {
+	info.savedHash.size = len(content)
 	info.savedHash.hash = hash
+	info.savedHash.content = append([]byte(nil), content...) // content can be the textarea buffer
 	info.UpdateFsDifferRowState()
 }
 
@@ -315,7 +324,9 @@ func (info *ERowInfo) NewFileERow(rowPos *ui.RowPos) (*ERow, error) {
 	}
 
 	// update data
-	info.setSavedHash(info.fsHash.hash, len(b))
+	info.setSavedHash(info.fsHash.hash, b)
+	info.format = info.fsFormat
+	info.warnFsFormat()
 
 	// new erow (no other rows exist)
 	erow := NewERow(info.Ed, info, rowPos)
@@ -331,7 +342,9 @@ func (info *ERowInfo) ReloadFile() error {
 	}
 
 	// update data
-	info.setSavedHash(info.fsHash.hash, len(b))
+	info.setSavedHash(info.fsHash.hash, b)
+	info.setFormat(info.fsFormat)
+	info.warnFsFormat()
 
 	// update all erows
 	info.SetRowsBytes(b)
@@ -396,11 +409,13 @@ func (info *ERowInfo) SaveFile() error {
 
 //----------
 
+// Returns the content decoded to utf8 with "\n" line endings.
 func (info *ERowInfo) readFsFile() ([]byte, error) {
-	b, err := ioutil.ReadFile(info.Name())
+	b, err := fsys.ReadFile(info.Name())
 	if err != nil {
 		return nil, err
 	}
+	b, info.fsFormat, info.fsFormatErr = encodingutil.Decode(b)
 
 	// update data
 	info.readFileInfo() // get new modtime
@@ -410,28 +425,96 @@ func (info *ERowInfo) readFsFile() ([]byte, error) {
 	return b, err
 }
 
+func (info *ERowInfo) warnFsFormat() {
+	if info.fsFormatErr != nil {
+		info.Ed.Errorf("%v: %v", info.Name(), info.fsFormatErr)
+	}
+}
+
+// Encodes the content with the file format.
 func (info *ERowInfo) saveFsFile(b []byte) error {
-	flags := os.O_WRONLY | os.O_TRUNC | os.O_CREATE
-	f, err := os.OpenFile(info.Name(), flags, 0644)
+	b2, err := encodingutil.Encode(b, info.format)
 	if err != nil {
 		return err
 	}
-	defer f.Close()
-	defer f.Sync() // necessary? modtime needs fsync on dir?
-	_, err = f.Write(b)
-	if err != nil {
+
+	if err := fsys.WriteFile(info.Name(), b2, 0644, info.Ed.SaveBackups); err != nil {
 		return err
 	}
 
 	// update data
 	h := bytesHash(b)
+	info.fsFormat = info.format
 	info.readFileInfo() // get new modtime
 	info.setFsHash(h)
-	info.setSavedHash(h, len(b))
+	info.setSavedHash(h, b)
+
+	return nil
+}
+
+//----------
 
+// Three-way merge of the rows content (edits since the last load/save) with the file on disk (changes by other programs). The result is set as one undoable edit, with conflict markers if the changes overlap.
+func (info *ERowInfo) Merge() (int, error) {
+	if !info.IsFileButNotDir() {
+		return 0, fmt.Errorf("not a file: %s", info.Name())
+	}
+	if len(info.ERows) == 0 {
+		return 0, nil
+	}
+	erow0 := info.ERows[0]
+	b, err := erow0.Row.TextArea.Bytes()
+	if err != nil {
+		return 0, err
+	}
+	merged, conflicts, err := info.mergeDisk(b)
+	if err != nil {
+		return 0, err
+	}
+	if !bytes.Equal(merged, b) {
+		if err := erow0.Row.TextArea.SetBytes(merged); err != nil {
+			return conflicts, err
+		}
+	}
+	info.UpdateEditedRowState()
+	return conflicts, nil
+}
+
+// Merges the edited content with the disk content. The disk content becomes the new base.
+func (info *ERowInfo) mergeDisk(b []byte) ([]byte, int, error) {
+	disk, err := info.readFsFile()
+	if err != nil {
+		return nil, 0, err
+	}
+	base := info.savedHash.content
+	merged, conflicts := diffutil.Merge3Bytes(base, b, disk, "edited", "disk")
+	info.setSavedHash(info.fsHash.hash, disk)
+	return merged, conflicts, nil
+}
+
+//----------
+
+func (info *ERowInfo) Format() encodingutil.Format {
+	return info.format
+}
+
+// Sets the format used on save. The rows show as edited if it differs from the file on disk.
+func (info *ERowInfo) SetFormat(f encodingutil.Format) error {
+	if !info.IsFileButNotDir() {
+		return fmt.Errorf("not a file: %s", info.Name())
+	}
+	info.setFormat(f)
+	info.UpdateEditedRowState()
 	return nil
 }
 
+func (info *ERowInfo) setFormat(f encodingutil.Format) {
+	info.format = f
+	for _, erow := range info.ERows {
+		erow.updateToolbarFormat()
+	}
+}
+
 //----------
 
 // Should be called under UI goroutine.
@@ -439,7 +522,79 @@ func (info *ERowInfo) UpdateDiskEvent() {
 	info.readFileInfo()
 	if info.IsFileButNotDir() {
 		info.updateFsHashIfNeeded()
+		info.autoReloadIfNeeded()
+	}
+}
+
+// Reloads the file if it changed on disk and the rows have no edits. Opt-in by the "-autoreload" option or the "$autoreload" row var.
+func (info *ERowInfo) autoReloadIfNeeded() {
+	if len(info.ERows) == 0 {
+		return
+	}
+	on := false
+	for _, erow := range info.ERows {
+		if erow.autoReloadOn() {
+			on = true
+			break
+		}
+	}
+	if !on {
+		return
+	}
+	if bytes.Equal(info.fsHash.hash, info.savedHash.hash) {
+		return
 	}
+	if !info.EqualToBytesHash(info.savedHash.size, info.savedHash.hash) {
+		return // edited
+	}
+	if err := info.reloadFileKeepPos(); err != nil {
+		info.Ed.Error(err)
+	}
+}
+
+// Reloads keeping the rows cursor, selection and scroll positions, mapped through a diff of the old and new content.
+func (info *ERowInfo) reloadFileKeepPos() error {
+	old, err := info.ERows[0].Row.TextArea.Bytes()
+	if err != nil {
+		return err
+	}
+	b, err := info.readFsFile()
+	if err != nil {
+		return err
+	}
+	m := diffutil.NewOffsetMapper(old, b)
+
+	type rowPos struct {
+		ci, si, offset int
+		selOn          bool
+	}
+	u := make([]rowPos, len(info.ERows))
+	for i, erow := range info.ERows {
+		ta := erow.Row.TextArea
+		tc := ta.TextCursor
+		u[i] = rowPos{
+			ci:     m.Map(tc.Index()),
+			si:     m.Map(tc.SelectionIndex()),
+			offset: m.Map(ta.RuneOffset()),
+			selOn:  tc.SelectionOn(),
+		}
+	}
+
+	info.setSavedHash(info.fsHash.hash, b)
+	info.setFormat(info.fsFormat)
+	info.SetRowsBytes(b)
+
+	for i, erow := range info.ERows {
+		ta := erow.Row.TextArea
+		p := u[i]
+		if p.selOn && p.si != p.ci {
+			ta.TextCursor.SetSelection(p.si, p.ci)
+		} else {
+			ta.TextCursor.SetIndex(p.ci)
+		}
+		ta.SetRuneOffset(p.offset)
+	}
+	return nil
 }
 
 //----------
@@ -465,6 +620,7 @@ func (info *ERowInfo) UpdateEditedRowState() {
 	}
 	info.editedHashNeedsUpdate()
 	edited := !info.EqualToBytesHash(info.savedHash.size, info.savedHash.hash)
+	edited = edited || info.format != info.fsFormat
 	info.updateRowsStates(ui.RowStateEdited, edited)
 }
 
@@ -0,0 +1,39 @@
+package core
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestERowInfoMerge1(t *testing.T) {
+	dir, err := ioutil.TempDir("", "erowinfo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fn := filepath.Join(dir, "a.txt")
+	info := &ERowInfo{Ed: &Editor{}, name: fn}
+
+	// save (the buffer is reused by the edits, like the textarea buffer)
+	buf := []byte("a\nb\nc\n")
+	if err := info.saveFsFile(buf); err != nil {
+		t.Fatal(err)
+	}
+	// edit
+	copy(buf, "A\nb\nc\n")
+	// external change
+	if err := ioutil.WriteFile(fn, []byte("a\nb\nC\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	merged, conflicts, err := info.mergeDisk(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conflicts != 0 || string(merged) != "A\nb\nC\n" {
+		t.Fatalf("%v %q", conflicts, merged)
+	}
+}
@@ -0,0 +1,169 @@
+package core
+
+import (
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jmigpin/editor/core/fswatcher"
+	"github.com/jmigpin/editor/core/fsys"
+)
+
+// Time to wait for more changes before running the cmd again (ex: saving several files).
+const erowWatchDebounce = 300 * time.Millisecond
+
+// Runs the row external cmd again when files under the row directory change (sub dirs included). Used from the ui goroutine.
+type ERowWatch struct {
+	erow    *ERow
+	on      bool
+	byVar   bool   // started by the "$watch" toolbar var
+	glob    string // empty matches all files
+	dir     string
+	hidden  bool   // match hidden files
+	refresh func() // runs instead of the external cmd (ex: dir listing)
+	timer   *time.Timer
+	timerN  int // ignore timers that were stopped too late
+}
+
+func NewERowWatch(erow *ERow) *ERowWatch {
+	return &ERowWatch{erow: erow}
+}
+
+//----------
+
+func (w *ERowWatch) Start(glob string, byVar bool) {
+	if w.on && w.refresh != nil {
+		w.Stop() // the external cmd replaces the refresh
+	}
+	if w.on {
+		w.glob = glob
+		return
+	}
+	w.on, w.glob, w.byVar = true, glob, byVar
+	w.start(false)
+}
+
+// Runs refresh when files under the row directory change (ex: "ListDir -sub").
+func (w *ERowWatch) StartRefresh(hidden bool, refresh func()) {
+	if w.on && w.refresh == nil {
+		return // already running the external cmd
+	}
+	if w.on && w.hidden != hidden {
+		w.Stop()
+	}
+	w.refresh = refresh
+	if w.on {
+		return
+	}
+	w.on = true
+	w.start(hidden)
+}
+
+func (w *ERowWatch) start(hidden bool) {
+	w.dir, w.hidden = w.erow.Info.Dir(), hidden
+	if !fsys.IsLocal(w.dir) {
+		return
+	}
+	if err := w.erow.Ed.Watcher.AddRecursive(w.dir, hidden); err != nil {
+		w.erow.Ed.Errorf("watch: %v", err)
+	}
+}
+
+func (w *ERowWatch) Stop() {
+	if !w.on {
+		return
+	}
+	w.on = false
+	w.refresh = nil
+	w.stopTimer()
+	if fsys.IsLocal(w.dir) {
+		_ = w.erow.Ed.Watcher.RemoveRecursive(w.dir)
+	}
+}
+
+// Stops the watch if it was started by StartRefresh.
+func (w *ERowWatch) StopRefresh() {
+	if w.refresh != nil {
+		w.Stop()
+	}
+}
+
+//----------
+
+func (w *ERowWatch) handleEvent(ev *fswatcher.Event) {
+	if !w.match(ev.Name) {
+		return
+	}
+	// files written by the cmd when exiting (ex: build outputs, flushed files) would run it again indefinitely
+	if w.refresh == nil && time.Since(w.erow.Exec.ended) < erowWatchDebounce {
+		return
+	}
+	// debounce
+	w.stopTimer()
+	n := w.timerN
+	w.timer = time.AfterFunc(erowWatchDebounce, func() {
+		w.erow.Ed.UI.RunOnUIGoRoutine(func() {
+			if !w.on || n != w.timerN {
+				return
+			}
+			w.timer = nil
+			if w.refresh != nil {
+				w.refresh()
+				return
+			}
+			if err := w.erow.Exec.Rerun(); err != nil {
+				w.erow.Ed.Errorf("watch: %v", err)
+			}
+		})
+	})
+}
+
+func (w *ERowWatch) stopTimer() {
+	if w.timer != nil {
+		w.timer.Stop()
+		w.timer = nil
+	}
+	w.timerN++
+}
+
+func (w *ERowWatch) match(name string) bool {
+	if !w.on {
+		return false
+	}
+	rel, err := filepath.Rel(w.dir, name)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return false
+	}
+	if w.refresh == nil && name == w.erow.teeFilename() {
+		return false // written by the cmd
+	}
+	base := filepath.Base(name)
+	if !w.hidden && strings.HasPrefix(base, ".") {
+		return false // hidden files (ex: editors temporary files)
+	}
+	if w.glob == "" {
+		return true
+	}
+	if m, _ := filepath.Match(w.glob, base); m {
+		return true
+	}
+	m, _ := filepath.Match(w.glob, rel)
+	return m
+}
+
+//----------
+
+// Runs the external cmd and runs it again when files change. A file row runs the cmd in a new row with the file directory.
+func ExternalCmdWatch(erow *ERow, cargs []string) {
+	if !erow.Info.IsFileButNotDir() && !erow.Info.IsDir() {
+		erow.Ed.Errorf("unable to run external cmd for erow: %v", erow.Info.Name())
+		return
+	}
+	env := populateEnvVars(erow, cargs)
+	erow2 := erow
+	if erow.Info.IsFileButNotDir() {
+		erow2 = newDirERowBelow(erow)
+	}
+	externalCmdDir(erow2, cargs, nil, env)
+	erow2.Watch.Start(erow.watchGlob, false)
+}
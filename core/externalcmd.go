@@ -1,13 +1,15 @@
 package core
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
-	"os"
-	"path/filepath"
+	"os/exec"
 	"strings"
+	"time"
 
+	"github.com/jmigpin/editor/core/fsys"
 	"github.com/jmigpin/editor/core/parseutil"
 	"github.com/jmigpin/editor/core/toolbarparser"
 	"github.com/jmigpin/editor/util/iout"
@@ -30,19 +32,35 @@ func ExternalCmdFromArgs(erow *ERow, cargs []string, fend func(error)) {
 	}
 }
 
+// Runs the cmd in a new row below with the row directory, keeping the row content and running cmd (ex: plumbing rule clicked in a cmd output).
+func ExternalCmdFromArgsNewRow(erow *ERow, cargs []string, fend func(error)) {
+	if !erow.Info.IsFileButNotDir() && !erow.Info.IsDir() {
+		erow.Ed.Errorf("unable to run external cmd for erow: %v", erow.Info.Name())
+		return
+	}
+	externalCmdFileButNotDir(erow, cargs, fend)
+}
+
 //----------
 
 // create a row with the file dir and run the cmd
 func externalCmdFileButNotDir(erow *ERow, cargs []string, fend func(error)) {
-	dir := filepath.Dir(erow.Info.Name())
-
-	info := erow.Ed.ReadERowInfo(dir)
-	rowPos := erow.Row.PosBelow()
-	erow2 := NewERow(erow.Ed, info, rowPos)
+	erow2 := newDirERowBelow(erow)
 
 	env := populateEnvVars(erow, cargs)
 
 	externalCmdDir(erow2, cargs, fend, env)
+
+	// $watch of the file row (the new row toolbar doesn't have it)
+	if erow.watchVar {
+		erow2.Watch.Start(erow.watchGlob, false)
+	}
+}
+
+func newDirERowBelow(erow *ERow) *ERow {
+	info := erow.Ed.ReadERowInfo(erow.Info.Dir())
+	rowPos := erow.Row.PosBelow()
+	return NewERow(erow.Ed, info, rowPos)
 }
 
 //----------
@@ -66,8 +84,9 @@ func populateEnvVars(erow *ERow, cargs []string) []string {
 			return cmdVar_getFileOffset(erow)
 		},
 	}
+	env := erow.Environ()
+
 	// populate env vars only if detected
-	env := os.Environ()
 	for k, v := range m {
 		for _, s := range cargs {
 			if parseutil.DetectEnvVar(s, k) {
@@ -103,9 +122,30 @@ func cmdVar_getLine(erow *ERow) string {
 //----------
 
 func externalCmdDir(erow *ERow, cargs []string, fend func(error), env []string) {
+	externalCmdDirInput(erow, cargs, fend, env, nil)
+}
+
+// If input is not nil, it is used as the cmd stdin instead of the text typed in the row.
+func externalCmdDirInput(erow *ERow, cargs []string, fend func(error), env []string, in []byte) {
 	if !erow.Info.IsDir() {
 		panic("not a directory")
 	}
+	// pseudo-terminal (before goroutine to avoid data race)
+	usePty := erow.pty && in == nil
+	useStdin := erow.stdin && in == nil
+	cols, rows := erow.textAreaSizeInRunes()
+
+	rerunArgs := cargs
+	if in != nil {
+		rerunArgs = nil // input not kept
+	}
+	erow.Exec.setRerun(rerunArgs, func() {
+		externalCmdDirInput(erow, cargs, fend, env, in)
+	})
+	if erow.watchVar {
+		erow.Watch.Start(erow.watchGlob, true)
+	}
+
 	erow.Exec.Start(func(ctx context.Context, w io.Writer) error {
 		// cleanup row content
 		erow.Ed.UI.RunOnUIGoRoutine(func() {
@@ -113,19 +153,45 @@ func externalCmdDir(erow *ERow, cargs []string, fend func(error), env []string)
 			erow.Row.TextArea.ClearPos()
 		})
 
-		err := externalCmdDir2(erow, cargs, env, ctx, w)
+		start := time.Now()
+		var err error
+		if usePty {
+			err = externalCmdDirPty(erow, cargs, env, ctx, cols, rows)
+		} else {
+			err = externalCmdDir2(erow, cargs, env, ctx, w, in, useStdin)
+		}
 		if fend != nil {
 			fend(err)
 		}
-		return err
+		return externalCmdExitStatus(erow, ctx, w, err, time.Since(start))
 	})
 }
 
-func externalCmdDir2(erow *ERow, cargs []string, env []string, ctx context.Context, w io.Writer) error {
+// Outputs a trailer with the exit code and duration. Returns the error only if the cmd didn't run (ex: not found).
+func externalCmdExitStatus(erow *ERow, ctx context.Context, w io.Writer, err error, dur time.Duration) error {
+	code := 0
+	if err != nil {
+		ee, ok := err.(*exec.ExitError)
+		if !ok {
+			erow.Exec.setExitStatus(ctx, false)
+			return err
+		}
+		code = ee.ExitCode()
+	}
+	dur = dur.Round(time.Millisecond)
+	if ctx.Err() != nil {
+		fmt.Fprintf(w, "# stopped (%v)\n", dur)
+	} else {
+		fmt.Fprintf(w, "# exit %v (%v)\n", code, dur)
+	}
+	erow.Exec.setExitStatus(ctx, code == 0 && ctx.Err() == nil)
+	return nil
+}
+
+// The cmd stdin is the input (if not nil), or a pipe for the text typed in the row (if useStdin), otherwise it reads nothing (null device).
+func externalCmdDir2(erow *ERow, cargs []string, env []string, ctx context.Context, w io.Writer, in []byte, useStdin bool) error {
 	// prepare cmd exec
-	cmd := osutil.ExecCmdCtxWithAttr(ctx, cargs)
-	cmd.Dir = erow.Info.Name()
-	cmd.Env = env
+	cmd := fsys.Command(ctx, erow.Info.Name(), cargs, env)
 
 	// Commented: cmd.wait() could block if the pipe readers are not closed
 	//cmd.Stdout = w
@@ -140,6 +206,16 @@ func externalCmdDir2(erow *ERow, cargs []string, env []string, ctx context.Conte
 	if err != nil {
 		return err
 	}
+	// stdin pipe: closed by cmd.wait()
+	var ipw io.WriteCloser
+	if in != nil {
+		cmd.Stdin = bytes.NewReader(in)
+	} else if useStdin {
+		ipw, err = cmd.StdinPipe()
+		if err != nil {
+			return err
+		}
+	}
 
 	// ensure concurrent writer
 	if _, ok := w.(*iout.AutoBufWriter); !ok {
@@ -162,6 +238,14 @@ func externalCmdDir2(erow *ERow, cargs []string, env []string, ctx context.Conte
 		return err
 	}
 
+	cargsStr := strings.Join(cargs, " ")
+	erow.Exec.setJobCmd(ctx, cmd, cargsStr)
+
+	// allow text typed in the row to be sent to the process
+	if ipw != nil {
+		erow.Exec.SetStdin(ctx, ipw)
+	}
+
 	// ensure kill to child processes on function exit (failsafe)
 	go func() {
 		select {
@@ -180,7 +264,6 @@ func externalCmdDir2(erow *ERow, cargs []string, env []string, ctx context.Conte
 
 	// TODO: ensure first output is pid with altered writer
 	// output pid
-	cargsStr := strings.Join(cargs, " ")
 	fmt.Fprintf(w, "# pid %d: %s\n", cmd.Process.Pid, cargsStr)
 
 	// wait for pipes close before calling wait() to avoid endless block
@@ -212,13 +295,22 @@ func cmdPartArgs(part *toolbarparser.Part) []string {
 //----------
 
 func shellCmdPartArgs(part *toolbarparser.Part) []string {
-	u := shellCmdPartArgsStr(part)
-	return osutil.ShellRunArgs(u...)
+	return ShellCmdArgs(part.Args)
 }
 
 func shellCmdPartArgsStr(part *toolbarparser.Part) []string {
+	return shellCmdArgsStr(part.Args)
+}
+
+// Args to run with the shell (ex: internal cmds that run an external cmd given as argument).
+func ShellCmdArgs(args []*toolbarparser.Arg) []string {
+	u := shellCmdArgsStr(args)
+	return osutil.ShellRunArgs(u...)
+}
+
+func shellCmdArgsStr(args []*toolbarparser.Arg) []string {
 	var u []string
-	for _, a := range part.Args {
+	for _, a := range args {
 		s := a.Str()
 		s = parseutil.RemoveEscapesEscapable(s, osutil.EscapeRune, "|")
 		u = append(u, s)
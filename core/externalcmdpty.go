@@ -0,0 +1,99 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jmigpin/editor/core/fsys"
+	"github.com/jmigpin/editor/util/osutil"
+	"github.com/jmigpin/editor/util/termutil"
+)
+
+// Runs the cmd in a pseudo-terminal. The output is interpreted by a terminal emulator (colors, cursor movements).
+func externalCmdDirPty(erow *ERow, cargs []string, env []string, ctx context.Context, cols, rows int) error {
+	master, slave, err := osutil.OpenPty()
+	if err != nil {
+		return err
+	}
+	defer master.Close()
+
+	if err := osutil.SetPtySize(master, cols, rows); err != nil {
+		slave.Close()
+		return err
+	}
+	// input is sent line by line from the row, echo would duplicate it
+	if err := osutil.SetPtyNoEcho(slave); err != nil {
+		slave.Close()
+		return err
+	}
+
+	// prepare cmd exec
+	cmd := fsys.Command(ctx, erow.Info.Name(), cargs, osutil.SetEnv(env, "TERM", "xterm-256color"))
+	osutil.SetupExecCmdPty(cmd)
+	cmd.Stdin = slave
+	cmd.Stdout = slave
+	cmd.Stderr = slave
+
+	// run command
+	err = cmd.Start()
+	slave.Close() // only the process keeps the slave open
+	if err != nil {
+		return err
+	}
+
+	cargsStr := strings.Join(cargs, " ")
+	erow.Exec.setJobCmd(ctx, cmd, cargsStr)
+
+	term := termutil.NewTerm(cols, rows)
+	erow.Exec.startTerm()
+
+	// allow text typed in the row to be sent to the process
+	erow.Exec.SetStdin(ctx, &ptyStdin{master})
+
+	// ensure kill to child processes on function exit (failsafe)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = osutil.KillExecCmd(cmd)
+			master.Close() // unblock read
+		}
+	}()
+
+	// output pid
+	pidStr := fmt.Sprintf("# pid %d: %s\r\n", cmd.Process.Pid, cargsStr)
+	<-erow.Exec.termUpdateAsync(term.Write([]byte(pidStr)))
+
+	// read loop (ends with an error when the process closes the slave)
+	var buf [4 * 1024]byte
+	for {
+		n, err := master.Read(buf[:])
+		if n > 0 {
+			upd := term.Write(buf[:n])
+			// Wait for the ui to have handled the content. This prevents a tight loop program from leaving the UI unresponsive.
+			<-erow.Exec.termUpdateAsync(upd)
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	return cmd.Wait()
+}
+
+//----------
+
+// Closing sends the EOF control char since the master can't be closed while reading.
+type ptyStdin struct {
+	f *os.File
+}
+
+func (p *ptyStdin) Write(b []byte) (int, error) {
+	return p.f.Write(b)
+}
+
+func (p *ptyStdin) Close() error {
+	_, err := p.f.Write([]byte{4}) // ctrl+d
+	return err
+}
@@ -5,14 +5,15 @@ import (
 	"path/filepath"
 	"runtime"
 
+	"github.com/jmigpin/editor/core/fsys"
 	"github.com/jmigpin/editor/util/goutil"
 )
 
 // Checks in GOROOT/GOPATH,  and some C include dirs.
 func FindFileInfo(name, dir string) (string, os.FileInfo, bool) {
 	// absolute path
-	if filepath.IsAbs(name) {
-		fi, err := os.Stat(name)
+	if fsys.IsAbs(name) {
+		fi, err := fsys.Stat(name)
 		if err == nil {
 			return name, fi, true
 		}
@@ -21,8 +22,8 @@ func FindFileInfo(name, dir string) (string, os.FileInfo, bool) {
 
 	// join with dir
 	{
-		u := filepath.Join(dir, name)
-		fi, err := os.Stat(u)
+		u := fsys.Join(dir, name)
+		fi, err := fsys.Stat(u)
 		if err == nil {
 			return u, fi, true
 		}
@@ -0,0 +1,123 @@
+package fswatcher
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+// Uses the fallback watcher for the paths that the watcher fails to add for reasons other than not existing (ex: inotify watches limit reached). The first failure is reported once as an error event. The events channel is closed after both watchers close theirs.
+type FallbackWatcher struct {
+	w, fw  Watcher
+	events chan interface{}
+	opMask Op
+	done   chan struct{}
+	wg     sync.WaitGroup
+	paths  struct {
+		sync.Mutex
+		m        map[string]bool // paths in the fallback watcher
+		reported bool
+	}
+}
+
+func NewFallbackWatcher(w, fw Watcher) *FallbackWatcher {
+	*w.OpMask() = AllOps
+	*fw.OpMask() = AllOps
+
+	fbw := &FallbackWatcher{w: w, fw: fw}
+	fbw.events = make(chan interface{})
+	fbw.opMask = AllOps
+	fbw.done = make(chan struct{})
+	fbw.paths.m = map[string]bool{}
+
+	fbw.wg.Add(2)
+	go fbw.eventLoop(w)
+	go fbw.eventLoop(fw)
+	go func() {
+		fbw.wg.Wait()
+		close(fbw.events)
+	}()
+	return fbw
+}
+
+//----------
+
+func (fbw *FallbackWatcher) Close() error {
+	close(fbw.done)
+	err := fbw.w.Close()
+	if err2 := fbw.fw.Close(); err == nil {
+		err = err2
+	}
+	return err
+}
+
+func (fbw *FallbackWatcher) OpMask() *Op {
+	return &fbw.opMask
+}
+
+//----------
+
+func (fbw *FallbackWatcher) Add(name string) error {
+	err := fbw.w.Add(name)
+	if err == nil || os.IsNotExist(err) {
+		return err
+	}
+	if err2 := fbw.fw.Add(name); err2 != nil {
+		return err2
+	}
+
+	fbw.paths.Lock()
+	defer fbw.paths.Unlock()
+	fbw.paths.m[name] = true
+	if !fbw.paths.reported {
+		fbw.paths.reported = true
+		err3 := fmt.Errorf("fswatcher: unable to watch %q: %v: polling instead (ex: increase fs.inotify.max_user_watches)", name, err)
+		// async: add might be called while handling events
+		fbw.wg.Add(1)
+		go func() {
+			defer fbw.wg.Done()
+			fbw.send(err3)
+		}()
+	}
+	return nil
+}
+
+func (fbw *FallbackWatcher) Remove(name string) error {
+	fbw.paths.Lock()
+	fb := fbw.paths.m[name]
+	delete(fbw.paths.m, name)
+	fbw.paths.Unlock()
+
+	if fb {
+		return fbw.fw.Remove(name)
+	}
+	return fbw.w.Remove(name)
+}
+
+//----------
+
+func (fbw *FallbackWatcher) Events() <-chan interface{} {
+	return fbw.events
+}
+
+func (fbw *FallbackWatcher) eventLoop(w Watcher) {
+	defer fbw.wg.Done()
+	for {
+		ev, ok := <-w.Events()
+		if !ok {
+			return
+		}
+		if ev2, ok := ev.(*Event); ok && ev2.Op&fbw.opMask == 0 {
+			continue
+		}
+		fbw.send(ev)
+	}
+}
+
+// Events are dropped after close (the watchers might still be sending while closing).
+func (fbw *FallbackWatcher) send(ev interface{}) {
+	select {
+	case fbw.events <- ev:
+	case <-fbw.done:
+	}
+}
@@ -54,6 +54,7 @@ func (w *FsnWatcher) Events() <-chan interface{} {
 //----------
 
 func (w *FsnWatcher) eventLoop() {
+	defer close(w.events)
 	for {
 		select {
 		case err, ok := <-w.w.Errors:
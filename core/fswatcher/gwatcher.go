@@ -13,10 +13,24 @@ import (
 type GWatcher struct {
 	w      Watcher
 	events chan interface{}
+	errs   chan error    // errors from other goroutines, sent by the event loop
+	done   chan struct{} // closed when the event loop ends
 	root   struct {
 		sync.Mutex
 		n *Node
 	}
+	rec struct {
+		sync.Mutex
+		m  map[string]*recWatch // recursive watches by root dir
+		wg sync.WaitGroup       // walks in progress
+	}
+}
+
+type recWatch struct {
+	hidden  bool
+	dirs    []string
+	refs    int  // number of AddRecursive calls not yet removed
+	removed bool // dirs found afterwards are not added
 }
 
 func NewGWatcher(w Watcher) *GWatcher {
@@ -24,11 +38,15 @@ func NewGWatcher(w Watcher) *GWatcher {
 
 	gw := &GWatcher{w: w}
 	gw.events = make(chan interface{})
+	gw.errs = make(chan error)
+	gw.done = make(chan struct{})
 
 	gw.root.Lock()
 	gw.root.n = NewNode(string(os.PathSeparator), nil)
 	gw.root.Unlock()
 
+	gw.rec.m = map[string]*recWatch{}
+
 	go gw.eventLoop()
 	return gw
 }
@@ -50,19 +68,32 @@ func (gw *GWatcher) Events() <-chan interface{} {
 }
 func (gw *GWatcher) eventLoop() {
 	defer close(gw.events)
+	defer close(gw.done)
 	for {
-		ev, ok := <-gw.w.Events()
-		if !ok {
-			break
-		}
-		switch t := ev.(type) {
-		case error:
-			gw.events <- t
-		case *Event:
-			gw.handleEv(t)
+		select {
+		case ev, ok := <-gw.w.Events():
+			if !ok {
+				return
+			}
+			switch t := ev.(type) {
+			case error:
+				gw.events <- t
+			case *Event:
+				gw.handleEv(t)
+			}
+		case err := <-gw.errs:
+			gw.events <- err
 		}
 	}
 }
+
+// Sends the error to the events channel. Used from goroutines other than the event loop.
+func (gw *GWatcher) sendErr(err error) {
+	select {
+	case gw.errs <- err:
+	case <-gw.done:
+	}
+}
 func (gw *GWatcher) handleEv(ev *Event) {
 	u := ev.Name
 	switch ev.Op {
@@ -71,29 +102,67 @@ func (gw *GWatcher) handleEv(ev *Event) {
 	case Modify:
 		_ = gw.modify(u)
 	}
+	_ = gw.dirChild(ev)
+	if ev.Op.HasAny(Create) {
+		gw.recCreate(u)
+	}
+}
+
+//----------
+
+// Sends events of direct childs of target directories (ex: a file modified inside a watched dir).
+func (gw *GWatcher) dirChild(ev *Event) error {
+	name := ev.Name
+	if err := gw.normalize(&name); err != nil {
+		return err
+	}
+
+	v := gw.split(name)
+	if len(v) == 0 {
+		return nil
+	}
+	gw.root.Lock()
+	defer gw.root.Unlock()
+	n, ok := gw.root.n.find(v[:len(v)-1])
+	if !ok || !n.isTarget() {
+		return nil
+	}
+	// already sent if the child is also a target
+	if c, ok := n.childs[v[len(v)-1]]; ok && c.isTarget() {
+		return nil
+	}
+	gw.events <- &Event{Op: ev.Op, Name: name}
+	return nil
 }
 
 //----------
 
+// Returns an error if the target fails to be watched for reasons other than not existing (it will be watched when it is created).
 func (gw *GWatcher) Add(name string) error {
 	if err := gw.normalize(&name); err != nil {
 		return err
 	}
+	return gw.add(name, func(n *Node) { n.target = true })
+}
 
+func (gw *GWatcher) add(name string, setTarget func(*Node)) error {
 	v := gw.split(name)
 	gw.root.Lock()
 	defer gw.root.Unlock()
+	var err error
 	gw.root.n.add(v, func(n *Node) {
-		p := n.path()
 		if !n.added {
-			err := gw.w.Add(p)
-			if err == nil {
+			err2 := gw.w.Add(n.path())
+			if err2 == nil {
 				n.added = true
+			} else if n.depth() == len(v) && !os.IsNotExist(err2) {
+				err = err2
 			}
 		}
 	})
-
-	return nil
+	n, _ := gw.root.n.find(v)
+	setTarget(n)
+	return err
 }
 
 //----------
@@ -109,13 +178,9 @@ func (gw *GWatcher) Remove(name string) error {
 	gw.root.n.remove(v, func(n *Node) {
 		if n.target {
 			n.target = false
-			if n.added {
-				n.added = false
-				p := n.path()
-				_ = gw.w.Remove(p)
-			}
+			gw.removeNodeWatch(n)
 		}
-		if len(n.childs) == 0 {
+		if len(n.childs) == 0 && !n.isTarget() {
 			n.delete()
 		}
 	})
@@ -123,6 +188,154 @@ func (gw *GWatcher) Remove(name string) error {
 	return nil
 }
 
+// Keeps the watch if the node is still a target of a recursive watch.
+func (gw *GWatcher) removeNodeWatch(n *Node) {
+	if n.added && !n.isTarget() {
+		n.added = false
+		_ = gw.w.Remove(n.path())
+	}
+}
+
+//----------
+
+// Watches the directory and its sub directories, including the ones created later. Hidden sub directories are skipped unless hidden is true. Calls for the same directory are counted, and need the same number of RemoveRecursive calls to remove the watch (hidden sub directories are watched if any of the calls asked for them).
+// The directories are walked in a goroutine, errors are sent to the events channel.
+func (gw *GWatcher) AddRecursive(name string, hidden bool) error {
+	if err := gw.normalize(&name); err != nil {
+		return err
+	}
+	gw.rec.Lock()
+	defer gw.rec.Unlock()
+	if rw, ok := gw.rec.m[name]; ok {
+		rw.refs++
+		addHidden := hidden && !rw.hidden
+		rw.hidden = rw.hidden || hidden
+		if addHidden {
+			// the dirs already watched are counted twice, and removed twice
+			gw.addRecursiveDirsAsync(rw, name, true)
+		}
+		return nil
+	}
+	rw := &recWatch{hidden: hidden, refs: 1}
+	gw.rec.m[name] = rw
+	gw.addRecursiveDirsAsync(rw, name, hidden)
+	return nil
+}
+
+// Needs the rec lock (to add to the waitgroup).
+func (gw *GWatcher) addRecursiveDirsAsync(rw *recWatch, dir string, hidden bool) {
+	gw.rec.wg.Add(1)
+	go func() {
+		defer gw.rec.wg.Done()
+		if err := gw.addRecursiveDirs(rw, dir, hidden); err != nil {
+			gw.sendErr(err)
+		}
+	}()
+}
+
+func (gw *GWatcher) addRecursiveDirs(rw *recWatch, dir string, hidden bool) error {
+	dirs := []string{}
+	_ = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.IsDir() {
+			return nil
+		}
+		if path != dir && !hidden && strings.HasPrefix(info.Name(), ".") {
+			return filepath.SkipDir
+		}
+		dirs = append(dirs, path)
+		return nil
+	})
+	if len(dirs) == 0 {
+		dirs = append(dirs, dir) // watched when created
+	}
+
+	// adding the dirs with the rec lock ensures a concurrent RemoveRecursive removes them
+	gw.rec.Lock()
+	defer gw.rec.Unlock()
+	if rw.removed {
+		return nil
+	}
+	rw.dirs = append(rw.dirs, dirs...)
+
+	var err error
+	for _, d := range dirs {
+		err2 := gw.add(d, func(n *Node) { n.recTargets++ })
+		if err2 != nil && err == nil {
+			err = err2
+		}
+	}
+	return err
+}
+
+func (gw *GWatcher) RemoveRecursive(name string) error {
+	if err := gw.normalize(&name); err != nil {
+		return err
+	}
+	gw.rec.Lock()
+	rw, ok := gw.rec.m[name]
+	if !ok {
+		gw.rec.Unlock()
+		return nil
+	}
+	rw.refs--
+	if rw.refs > 0 {
+		gw.rec.Unlock()
+		return nil
+	}
+	delete(gw.rec.m, name)
+	rw.removed = true
+	dirs := append([]string{}, rw.dirs...)
+	gw.rec.Unlock()
+
+	// deepest first to delete the nodes without childs
+	sort.Slice(dirs, func(i, j int) bool {
+		return len(dirs[i]) > len(dirs[j])
+	})
+
+	gw.root.Lock()
+	defer gw.root.Unlock()
+	for _, d := range dirs {
+		n, ok := gw.root.n.find(gw.split(d))
+		if !ok {
+			continue
+		}
+		n.recTargets--
+		gw.removeNodeWatch(n)
+		for n.parent != nil && len(n.childs) == 0 && !n.isTarget() {
+			n.delete()
+			n = n.parent
+		}
+	}
+	return nil
+}
+
+// Watches directories created inside recursive watches.
+func (gw *GWatcher) recCreate(name string) {
+	if err := gw.normalize(&name); err != nil {
+		return
+	}
+	fi, err := os.Stat(name)
+	if err != nil || !fi.IsDir() {
+		return
+	}
+	gw.rec.Lock()
+	var rw *recWatch
+	var dir string
+	for d, rw2 := range gw.rec.m {
+		if isSubPath(d, name) && len(d) > len(dir) {
+			rw, dir = rw2, d
+		}
+	}
+	gw.rec.Unlock()
+	if rw == nil {
+		return
+	}
+	if !rw.hidden && strings.HasPrefix(filepath.Base(name), ".") {
+		return
+	}
+	_ = gw.addRecursiveDirs(rw, name, rw.hidden)
+}
+
 //----------
 
 func (gw *GWatcher) review(name string) error {
@@ -138,7 +351,7 @@ func (gw *GWatcher) review(name string) error {
 		err := gw.w.Add(p)
 		wasAdded := n.added
 		n.added = err == nil
-		if n.target {
+		if n.isTarget() {
 			if !wasAdded && n.added {
 				gw.events <- &Event{Op: Create, Name: p}
 			}
@@ -162,7 +375,7 @@ func (gw *GWatcher) modify(name string) error {
 	gw.root.Lock()
 	defer gw.root.Unlock()
 	gw.root.n.modify(v, func(n *Node) {
-		if n.target {
+		if n.isTarget() {
 			p := n.path()
 			gw.events <- &Event{Op: Modify, Name: p}
 		}
@@ -200,8 +413,9 @@ type Node struct {
 	childs map[string]*Node
 	parent *Node
 
-	target bool
-	added  bool
+	target     bool
+	recTargets int // number of recursive watches that include the node
+	added      bool
 }
 
 func NewNode(name string, parent *Node) *Node {
@@ -213,6 +427,17 @@ func NewNode(name string, parent *Node) *Node {
 	return n
 }
 
+func (n *Node) isTarget() bool {
+	return n.target || n.recTargets > 0
+}
+
+func (n *Node) depth() int {
+	if n.parent == nil {
+		return 0
+	}
+	return n.parent.depth() + 1
+}
+
 func (n *Node) delete() {
 	if n.parent != nil {
 		delete(n.parent.childs, n.name)
@@ -243,9 +468,6 @@ func (n *Node) visit(v []string, create, visSubChilds, depthFirst bool, fn func(
 		}
 		c = NewNode(k, n)
 	}
-	if create && len(v) == 1 {
-		c.target = true
-	}
 	c.visit(v[1:], create, visSubChilds, depthFirst, fn)
 }
 
@@ -264,6 +486,17 @@ func (n *Node) modify(v []string, fn func(*Node)) {
 	n.visit(v, false, false, false, fn)
 }
 
+func (n *Node) find(v []string) (*Node, bool) {
+	for _, k := range v {
+		c, ok := n.childs[k]
+		if !ok {
+			return nil, false
+		}
+		n = c
+	}
+	return n, true
+}
+
 //----------
 
 func (n *Node) path() string {
@@ -295,3 +528,14 @@ func (n *Node) SprintFlatTree() string {
 	s += "}"
 	return s
 }
+
+//----------
+
+// Name is dir or is inside dir.
+func isSubPath(dir, name string) bool {
+	rel, err := filepath.Rel(dir, name)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -250,3 +250,151 @@ func TestGWatcher6(t *testing.T) {
 		t.Fatalf(s)
 	}
 }
+
+func TestGWatcher7(t *testing.T) {
+	tmpDir := tmpDir()
+	defer os.RemoveAll(tmpDir)
+
+	w := NewGWatcher(mustNewFsnWatcher(t))
+	defer w.Close()
+
+	dir := tmpDir
+	dir2 := filepath.Join(dir, "dir2")
+	file1 := filepath.Join(dir2, "file1.txt")
+
+	mustMkdirAll(t, dir2)
+	mustAddWatch(t, w, dir2)
+	mustCreateFile(t, file1)
+
+	// event of a child of a watched dir
+	readEvent(t, w, true, func(ev *Event) bool {
+		return ev.Name == file1 && ev.Op.HasAny(Create)
+	})
+
+	mustRemoveWatch(t, w, dir2)
+
+	s := w.root.n.SprintFlatTree()
+	if s != "{/:}" {
+		t.Fatalf(s)
+	}
+}
+
+func TestGWatcherRecursive1(t *testing.T) {
+	tmpDir := tmpDir()
+	defer os.RemoveAll(tmpDir)
+
+	w := NewGWatcher(mustNewFsnWatcher(t))
+	defer w.Close()
+
+	dir := tmpDir
+	dir2 := filepath.Join(dir, "dir2")
+	dir3 := filepath.Join(dir2, "dir3")
+	file1 := filepath.Join(dir2, "file1.txt")
+	file2 := filepath.Join(dir3, "file2.txt")
+
+	mustMkdirAll(t, dir2)
+	if err := w.AddRecursive(dir, false); err != nil {
+		t.Fatal(err)
+	}
+	w.rec.wg.Wait()
+
+	mustCreateFile(t, file1)
+
+	readEvent(t, w, true, func(ev *Event) bool {
+		return ev.Name == file1 && ev.Op.HasAny(Create)
+	})
+
+	// dir created after the watch was added
+	mustMkdirAll(t, dir3)
+
+	readEvent(t, w, true, func(ev *Event) bool {
+		return ev.Name == dir3 && ev.Op.HasAny(Create)
+	})
+
+	mustCreateFile(t, file2)
+
+	readEvent(t, w, true, func(ev *Event) bool {
+		return ev.Name == file2 && ev.Op.HasAny(Create)
+	})
+
+	// the watch of a target is kept
+	mustAddWatch(t, w, dir2)
+	if err := w.RemoveRecursive(dir); err != nil {
+		t.Fatal(err)
+	}
+	mustRemoveWatch(t, w, dir2)
+
+	s := w.root.n.SprintFlatTree()
+	if s != "{/:}" {
+		t.Fatalf(s)
+	}
+}
+
+func TestGWatcherRecursive2(t *testing.T) {
+	tmpDir := tmpDir()
+	defer os.RemoveAll(tmpDir)
+
+	w := NewGWatcher(mustNewFsnWatcher(t))
+
+	dir := tmpDir
+	file1 := filepath.Join(dir, "file1.txt")
+
+	// two users of the same dir
+	for i := 0; i < 2; i++ {
+		if err := w.AddRecursive(dir, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w.rec.wg.Wait()
+	if err := w.RemoveRecursive(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	// still watching
+	mustCreateFile(t, file1)
+	readEvent(t, w, true, func(ev *Event) bool {
+		return ev.Name == file1 && ev.Op.HasAny(Create)
+	})
+
+	if err := w.RemoveRecursive(dir); err != nil {
+		t.Fatal(err)
+	}
+	s := w.root.n.SprintFlatTree()
+	if s != "{/:}" {
+		t.Fatalf(s)
+	}
+
+	// events channel is closed
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for range w.Events() {
+	}
+}
+
+func TestGWatcherRecursive3(t *testing.T) {
+	tmpDir := tmpDir()
+	defer os.RemoveAll(tmpDir)
+
+	w := NewGWatcher(mustNewFsnWatcher(t))
+	defer w.Close()
+
+	dir := tmpDir
+	mustMkdirAll(t, filepath.Join(dir, "dir2", "dir3"))
+
+	// removed before the walk ends: dirs found are not added
+	if err := w.AddRecursive(dir, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.RemoveRecursive(dir); err != nil {
+		t.Fatal(err)
+	}
+	w.rec.wg.Wait()
+
+	w.root.Lock()
+	s := w.root.n.SprintFlatTree()
+	w.root.Unlock()
+	if s != "{/:}" {
+		t.Fatalf(s)
+	}
+}
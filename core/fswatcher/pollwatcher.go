@@ -0,0 +1,199 @@
+package fswatcher
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Watcher that stats the paths periodically. Directories are listed to send the events of their direct childs (like inotify). Used for paths that can't be watched by the system (ex: inotify watches limit).
+type PollWatcher struct {
+	interval time.Duration
+	events   chan interface{}
+	opMask   Op
+	stop     chan struct{}
+	paths    struct {
+		sync.Mutex
+		m map[string]*pollState
+	}
+}
+
+func NewPollWatcher(interval time.Duration) *PollWatcher {
+	w := &PollWatcher{
+		interval: interval,
+		events:   make(chan interface{}),
+		stop:     make(chan struct{}),
+	}
+	w.opMask = AllOps
+	w.paths.m = map[string]*pollState{}
+	go w.pollLoop()
+	return w
+}
+
+//----------
+
+func (w *PollWatcher) Close() error {
+	close(w.stop)
+	return nil
+}
+
+func (w *PollWatcher) OpMask() *Op {
+	return &w.opMask
+}
+
+//----------
+
+// Fails if the path doesn't exist (like inotify).
+func (w *PollWatcher) Add(name string) error {
+	ps, err := readPollState(name)
+	if err != nil {
+		return err
+	}
+	w.paths.Lock()
+	defer w.paths.Unlock()
+	w.paths.m[name] = ps
+	return nil
+}
+
+func (w *PollWatcher) Remove(name string) error {
+	w.paths.Lock()
+	defer w.paths.Unlock()
+	delete(w.paths.m, name)
+	return nil
+}
+
+//----------
+
+func (w *PollWatcher) Events() <-chan interface{} {
+	return w.events
+}
+
+//----------
+
+func (w *PollWatcher) pollLoop() {
+	defer close(w.events)
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			for _, ev := range w.poll() {
+				if ev.Op&w.opMask == 0 {
+					continue
+				}
+				select {
+				case <-w.stop:
+					return
+				case w.events <- ev:
+				}
+			}
+		}
+	}
+}
+
+func (w *PollWatcher) poll() []*Event {
+	w.paths.Lock()
+	names := []string{}
+	for name := range w.paths.m {
+		names = append(names, name)
+	}
+	w.paths.Unlock()
+
+	evs := []*Event{}
+	for _, name := range names {
+		ps2, err := readPollState(name)
+
+		w.paths.Lock()
+		ps, ok := w.paths.m[name]
+		if !ok { // removed meanwhile
+			w.paths.Unlock()
+			continue
+		}
+		if err != nil {
+			// the watch is dropped (like inotify), needs to be added again
+			delete(w.paths.m, name)
+		} else {
+			w.paths.m[name] = ps2
+		}
+		w.paths.Unlock()
+
+		evs = append(evs, ps.diff(name, ps2)...)
+	}
+	return evs
+}
+
+//----------
+
+type pollState struct {
+	fi     os.FileInfo
+	childs map[string]os.FileInfo // directory entries
+}
+
+func readPollState(name string) (*pollState, error) {
+	fi, err := os.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	ps := &pollState{fi: fi}
+	if fi.IsDir() {
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		fis, err := f.Readdir(-1)
+		if err != nil {
+			return nil, err
+		}
+		ps.childs = map[string]os.FileInfo{}
+		for _, fi2 := range fis {
+			ps.childs[fi2.Name()] = fi2
+		}
+	}
+	return ps, nil
+}
+
+// A nil ps2 means the path doesn't exist anymore.
+func (ps *pollState) diff(name string, ps2 *pollState) []*Event {
+	if ps2 == nil {
+		return []*Event{{Op: Remove, Name: name}}
+	}
+	evs := []*Event{}
+	if op := fileInfoOp(ps.fi, ps2.fi); op != 0 {
+		evs = append(evs, &Event{Op: op, Name: name})
+	}
+	for k, fi := range ps.childs {
+		name2 := filepath.Join(name, k)
+		fi2, ok := ps2.childs[k]
+		if !ok {
+			evs = append(evs, &Event{Op: Remove, Name: name2})
+			continue
+		}
+		if op := fileInfoOp(fi, fi2); op != 0 {
+			evs = append(evs, &Event{Op: op, Name: name2})
+		}
+	}
+	for k := range ps2.childs {
+		if _, ok := ps.childs[k]; !ok {
+			evs = append(evs, &Event{Op: Create, Name: filepath.Join(name, k)})
+		}
+	}
+	return evs
+}
+
+func fileInfoOp(fi, fi2 os.FileInfo) Op {
+	var op Op
+	if fi.Size() != fi2.Size() || !fi.ModTime().Equal(fi2.ModTime()) {
+		// a directory modtime changes with its entries, the childs events are sent instead
+		if !fi2.IsDir() {
+			op.Add(Modify)
+		}
+	}
+	if fi.Mode() != fi2.Mode() {
+		op.Add(Attrib)
+	}
+	return op
+}
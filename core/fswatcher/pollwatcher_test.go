@@ -0,0 +1,111 @@
+package fswatcher
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestPollWatcher1(t *testing.T) {
+	tmpDir := tmpDir()
+	defer os.RemoveAll(tmpDir)
+
+	w := NewPollWatcher(20 * time.Millisecond)
+	defer w.Close()
+
+	dir := tmpDir
+	file1 := filepath.Join(dir, "file1.txt")
+
+	if err := w.Add(filepath.Join(dir, "nonexistent")); !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+
+	mustAddWatch(t, w, dir)
+	mustCreateFile(t, file1)
+
+	readEvent(t, w, true, func(ev *Event) bool {
+		return ev.Name == file1 && ev.Op.HasAny(Create)
+	})
+
+	mustWriteFile(t, file1)
+
+	readEvent(t, w, true, func(ev *Event) bool {
+		return ev.Name == file1 && ev.Op.HasAny(Modify)
+	})
+
+	mustRemoveAll(t, file1)
+
+	readEvent(t, w, true, func(ev *Event) bool {
+		return ev.Name == file1 && ev.Op.HasAny(Remove)
+	})
+
+	mustRemoveAll(t, dir)
+
+	readEvent(t, w, true, func(ev *Event) bool {
+		return ev.Name == dir && ev.Op.HasAny(Remove)
+	})
+}
+
+//----------
+
+func TestFallbackWatcher1(t *testing.T) {
+	tmpDir := tmpDir()
+	defer os.RemoveAll(tmpDir)
+
+	pw := NewPollWatcher(20 * time.Millisecond)
+	w := NewGWatcher(NewFallbackWatcher(newLimitWatcher(), pw))
+
+	dir := tmpDir
+	file1 := filepath.Join(dir, "file1.txt")
+
+	mustAddWatch(t, w, file1)
+
+	// reported once
+	ev := <-w.Events()
+	if err, ok := ev.(error); !ok || !strings.Contains(err.Error(), "polling") {
+		t.Fatal(ev)
+	}
+
+	mustCreateFile(t, file1)
+
+	readEvent(t, w, true, func(ev *Event) bool {
+		return ev.Name == file1 && ev.Op.HasAny(Create)
+	})
+
+	mustRemoveWatch(t, w, file1)
+
+	s := w.root.n.SprintFlatTree()
+	if s != "{/:}" {
+		t.Fatalf(s)
+	}
+
+	// events channel is closed
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for range w.Events() {
+	}
+}
+
+//----------
+
+// Fails to add watches as if the inotify watches limit was reached.
+type limitWatcher struct {
+	opMask Op
+	events chan interface{}
+}
+
+func newLimitWatcher() *limitWatcher {
+	return &limitWatcher{events: make(chan interface{})}
+}
+
+func (w *limitWatcher) Add(name string) error {
+	return os.NewSyscallError("inotify_add_watch", syscall.ENOSPC)
+}
+func (w *limitWatcher) Remove(name string) error   { return nil }
+func (w *limitWatcher) Events() <-chan interface{} { return w.events }
+func (w *limitWatcher) OpMask() *Op                { return &w.opMask }
+func (w *limitWatcher) Close() error               { close(w.events); return nil }
@@ -0,0 +1,144 @@
+// Filesystem layer used by the rows: local files, or remote files with names like "ssh://host/path".
+package fsys
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/jmigpin/editor/util/osutil"
+)
+
+type FS interface {
+	Stat(name string) (os.FileInfo, error)
+	ReadFile(name string) ([]byte, error)
+	// Should not leave a truncated file on failure. If backup is true, the previous content is kept in "<name>~".
+	WriteFile(name string, b []byte, perm os.FileMode, backup bool) error
+	ReadDir(name string) ([]os.FileInfo, error)
+	// Cmd that runs args in dir with env.
+	Command(ctx context.Context, dir string, args []string, env []string) *exec.Cmd
+}
+
+//----------
+
+const sshPrefix = "ssh://"
+
+var Local FS = &LocalFS{}
+
+// Returns the filesystem of the name, and the path inside it.
+func Get(name string) (FS, string) {
+	if host, p, ok := splitSsh(name); ok {
+		return &SshFS{Host: host}, p
+	}
+	return Local, name
+}
+
+func IsLocal(name string) bool {
+	_, _, ok := splitSsh(name)
+	return !ok
+}
+
+// "ssh://host/a/b" -> "host", "/a/b". Hosts starting with "-" are not accepted (would be read as ssh options).
+func splitSsh(name string) (string, string, bool) {
+	if !strings.HasPrefix(name, sshPrefix) {
+		return "", "", false
+	}
+	s := name[len(sshPrefix):]
+	host, p := s, "/"
+	if i := strings.Index(s, "/"); i >= 0 {
+		host, p = s[:i], s[i:]
+	}
+	if host == "" || strings.HasPrefix(host, "-") {
+		return "", "", false
+	}
+	return host, p, true
+}
+
+//----------
+
+// Path funcs that keep the remote prefix.
+
+func Clean(name string) string {
+	if host, p, ok := splitSsh(name); ok {
+		return sshPrefix + host + path.Clean(p)
+	}
+	return osutil.FilepathClean(name)
+}
+
+func Dir(name string) string {
+	if host, p, ok := splitSsh(name); ok {
+		return sshPrefix + host + path.Dir(p)
+	}
+	return filepath.Dir(name)
+}
+
+func Join(dir string, elem ...string) string {
+	if host, p, ok := splitSsh(dir); ok {
+		u := append([]string{p}, elem...)
+		return sshPrefix + host + path.Join(u...)
+	}
+	return filepath.Join(append([]string{dir}, elem...)...)
+}
+
+func IsAbs(name string) bool {
+	return !IsLocal(name) || filepath.IsAbs(name)
+}
+
+//----------
+
+func Stat(name string) (os.FileInfo, error) {
+	fs, p := Get(name)
+	return fs.Stat(p)
+}
+
+func ReadFile(name string) ([]byte, error) {
+	fs, p := Get(name)
+	return fs.ReadFile(p)
+}
+
+func WriteFile(name string, b []byte, perm os.FileMode, backup bool) error {
+	fs, p := Get(name)
+	return fs.WriteFile(p, b, perm, backup)
+}
+
+func ReadDir(name string) ([]os.FileInfo, error) {
+	fs, p := Get(name)
+	return fs.ReadDir(p)
+}
+
+func Command(ctx context.Context, dir string, args []string, env []string) *exec.Cmd {
+	fs, p := Get(dir)
+	return fs.Command(ctx, p, args, env)
+}
+
+//----------
+
+type LocalFS struct{}
+
+func (fs *LocalFS) Stat(name string) (os.FileInfo, error) {
+	return os.Stat(name)
+}
+func (fs *LocalFS) ReadFile(name string) ([]byte, error) {
+	return ioutil.ReadFile(name)
+}
+func (fs *LocalFS) WriteFile(name string, b []byte, perm os.FileMode, backup bool) error {
+	return osutil.WriteFileAtomic(name, b, perm, backup)
+}
+func (fs *LocalFS) ReadDir(name string) ([]os.FileInfo, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return f.Readdir(-1)
+}
+func (fs *LocalFS) Command(ctx context.Context, dir string, args []string, env []string) *exec.Cmd {
+	cmd := osutil.ExecCmdCtxWithAttr(ctx, args)
+	cmd.Dir = dir
+	cmd.Env = env
+	return cmd
+}
@@ -0,0 +1,323 @@
+package fsys
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jmigpin/editor/util/osutil"
+)
+
+// Cmd used to reach the host, "--", the host and the remote cmd line are appended (ex: can be replaced by a local stand-in).
+var SshCmd = []string{"ssh", "-o", "BatchMode=yes", "-o", "ConnectTimeout=5"}
+
+// Timeout of the file operations (the cmds run without timeout).
+var SshTimeout = 30 * time.Second
+
+// Stat results are reused for this time (the stats are done often from the ui goroutine, ex: clicking filenames, opening rows).
+var SshStatCacheTime = 5 * time.Second
+
+// Operations on a host that failed to connect fail without trying again for this time (an unreachable host would otherwise block the editor on each operation).
+var SshRetryTime = 15 * time.Second
+
+// Runs the operations on the host through an ssh subprocess. The host needs a posix shell.
+type SshFS struct {
+	Host string
+}
+
+// exit code of the scripts if the file doesn't exist
+const sshNotExistCode = 3
+
+// exit code of ssh on connection errors
+const sshConnErrCode = 255
+
+func (fs *SshFS) Stat(name string) (os.FileInfo, error) {
+	key := fs.Host + name
+	if fi, err, ok := sshStats.get(key); ok {
+		return fi, err
+	}
+	fi, err := fs.stat(name)
+	sshStats.set(key, fi, err)
+	return fi, err
+}
+
+func (fs *SshFS) stat(name string) (os.FileInfo, error) {
+	script := `[ -e "$1" ] || exit 3
+stat -L -c '%f %s %Y' -- "$1" 2>/dev/null || stat -L -f '%Xp %z %m' -- "$1"`
+	out, err := fs.run("stat", name, script, nil, name)
+	if err != nil {
+		return nil, err
+	}
+	var mode uint32
+	var size, mtime int64
+	if _, err := fmt.Sscanf(string(out), "%x %d %d", &mode, &size, &mtime); err != nil {
+		return nil, fs.pathError("stat", name, fmt.Errorf("unexpected output: %q", out))
+	}
+	fi := &fileInfo{
+		name:    path.Base(name),
+		size:    size,
+		mode:    unixFileMode(mode),
+		modTime: time.Unix(mtime, 0),
+	}
+	return fi, nil
+}
+
+func (fs *SshFS) ReadFile(name string) ([]byte, error) {
+	script := `[ -e "$1" ] || exit 3
+exec cat -- "$1"`
+	return fs.run("read", name, script, nil, name)
+}
+
+// Writes to a temporary file in the same directory and renames it over the target (symlinks are followed). Falls back to writing in place if the temporary file can't be created.
+func (fs *SshFS) WriteFile(name string, b []byte, perm os.FileMode, backup bool) error {
+	script := `p=$1
+while [ -L "$p" ]; do
+	l=$(readlink -- "$p")
+	case $l in
+	/*) p=$l ;;
+	*) p=$(dirname -- "$p")/$l ;;
+	esac
+done
+if [ -e "$p" ] && [ "$2" = 1 ]; then cp -p -- "$p" "$p~" || exit 1; fi
+t=$(dirname -- "$p")/.$(basename -- "$p").tmp$$
+if [ -e "$p" ]; then
+	cp -p -- "$p" "$t" 2>/dev/null
+else
+	(umask 077; : > "$t") 2>/dev/null && chmod "$3" "$t"
+fi
+if [ $? -ne 0 ]; then exec cat > "$p"; fi
+cat > "$t" && mv -f -- "$t" "$p" && exit 0
+rm -f -- "$t"
+exit 1`
+	bk := "0"
+	if backup {
+		bk = "1"
+	}
+	mode := strconv.FormatUint(uint64(perm.Perm()), 8)
+	_, err := fs.run("write", name, script, b, name, bk, mode)
+	sshStats.remove(fs.Host + name)
+	sshStats.remove(fs.Host + name + "~")
+	return err
+}
+
+func (fs *SshFS) ReadDir(name string) ([]os.FileInfo, error) {
+	script := `[ -e "$1" ] || exit 3
+cd -- "$1" || exit 1
+for f in .* *; do
+	case $f in .|..) continue ;; esac
+	[ -e "$f" ] || [ -L "$f" ] || continue
+	if [ -d "$f" ]; then printf 'd%s\0' "$f"; else printf 'f%s\0' "$f"; fi
+done`
+	out, err := fs.run("readdir", name, script, nil, name)
+	if err != nil {
+		return nil, err
+	}
+	u := []os.FileInfo{}
+	for _, s := range strings.Split(string(out), "\x00") {
+		if len(s) < 2 {
+			continue
+		}
+		fi := &fileInfo{name: s[1:]}
+		if s[0] == 'd' {
+			fi.mode = os.ModeDir
+		}
+		u = append(u, fi)
+	}
+	return u, nil
+}
+
+// The env vars that differ from the editor process are set in the remote cmd.
+func (fs *SshFS) Command(ctx context.Context, dir string, args []string, env []string) *exec.Cmd {
+	script := `cd -- "$1" || exit 1
+shift
+exec env "$@"`
+	u := []string{dir}
+	u = append(u, envDiff(os.Environ(), env)...)
+	u = append(u, args...)
+	return fs.cmd(ctx, script, u...)
+}
+
+//----------
+
+func (fs *SshFS) cmd(ctx context.Context, script string, args ...string) *exec.Cmd {
+	// the remote shell parses the cmd line
+	u := []string{"sh", "-c", script, "sh"}
+	u = append(u, args...)
+	q := []string{}
+	for _, s := range u {
+		q = append(q, shellQuote(s))
+	}
+	a := append([]string{}, SshCmd...)
+	a = append(a, "--", fs.Host, strings.Join(q, " "))
+	return osutil.ExecCmdCtxWithAttr(ctx, a)
+}
+
+func (fs *SshFS) run(op, name, script string, in []byte, args ...string) ([]byte, error) {
+	if err := sshHosts.check(fs.Host); err != nil {
+		return nil, fs.pathError(op, name, err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), SshTimeout)
+	defer cancel()
+	cmd := fs.cmd(ctx, script, args...)
+	if in != nil {
+		cmd.Stdin = bytes.NewReader(in)
+	}
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if ee, ok := err.(*exec.ExitError); ok {
+			switch ee.ExitCode() {
+			case sshNotExistCode:
+				return nil, fs.pathError(op, name, os.ErrNotExist)
+			case sshConnErrCode:
+				sshHosts.setDown(fs.Host)
+			}
+		}
+		if s := strings.TrimSpace(stderr.String()); s != "" {
+			err = fmt.Errorf("%v: %v", err, s)
+		}
+		return nil, fs.pathError(op, name, err)
+	}
+	return out, nil
+}
+
+func (fs *SshFS) pathError(op, name string, err error) error {
+	return &os.PathError{Op: op, Path: sshPrefix + fs.Host + name, Err: err}
+}
+
+//----------
+
+var sshStats = &statCache{m: map[string]*statCacheEntry{}}
+
+type statCache struct {
+	sync.Mutex
+	m map[string]*statCacheEntry
+}
+
+type statCacheEntry struct {
+	fi  os.FileInfo
+	err error
+	t   time.Time
+}
+
+func (c *statCache) get(key string) (os.FileInfo, error, bool) {
+	c.Lock()
+	defer c.Unlock()
+	e, ok := c.m[key]
+	if !ok || time.Since(e.t) > SshStatCacheTime {
+		return nil, nil, false
+	}
+	return e.fi, e.err, true
+}
+
+func (c *statCache) set(key string, fi os.FileInfo, err error) {
+	c.Lock()
+	defer c.Unlock()
+	// drop expired entries
+	for k, e := range c.m {
+		if time.Since(e.t) > SshStatCacheTime {
+			delete(c.m, k)
+		}
+	}
+	c.m[key] = &statCacheEntry{fi: fi, err: err, t: time.Now()}
+}
+
+func (c *statCache) remove(key string) {
+	c.Lock()
+	defer c.Unlock()
+	delete(c.m, key)
+}
+
+//----------
+
+var sshHosts = &hostsState{down: map[string]time.Time{}}
+
+type hostsState struct {
+	sync.Mutex
+	down map[string]time.Time
+}
+
+func (hs *hostsState) check(host string) error {
+	hs.Lock()
+	defer hs.Unlock()
+	t, ok := hs.down[host]
+	if !ok {
+		return nil
+	}
+	if d := SshRetryTime - time.Since(t); d > 0 {
+		return fmt.Errorf("host unreachable (retrying in %v)", d.Round(time.Second))
+	}
+	delete(hs.down, host)
+	return nil
+}
+
+func (hs *hostsState) setDown(host string) {
+	hs.Lock()
+	defer hs.Unlock()
+	hs.down[host] = time.Now()
+}
+
+//----------
+
+type fileInfo struct {
+	name    string
+	size    int64
+	mode    os.FileMode
+	modTime time.Time
+}
+
+func (fi *fileInfo) Name() string       { return fi.name }
+func (fi *fileInfo) Size() int64        { return fi.size }
+func (fi *fileInfo) Mode() os.FileMode  { return fi.mode }
+func (fi *fileInfo) ModTime() time.Time { return fi.modTime }
+func (fi *fileInfo) IsDir() bool        { return fi.mode.IsDir() }
+func (fi *fileInfo) Sys() interface{}   { return nil }
+
+// From the unix st_mode.
+func unixFileMode(m uint32) os.FileMode {
+	mode := os.FileMode(m & 0777)
+	switch m & 0170000 {
+	case 0040000:
+		mode |= os.ModeDir
+	case 0120000:
+		mode |= os.ModeSymlink
+	case 0020000:
+		mode |= os.ModeDevice | os.ModeCharDevice
+	case 0060000:
+		mode |= os.ModeDevice
+	case 0010000:
+		mode |= os.ModeNamedPipe
+	case 0140000:
+		mode |= os.ModeSocket
+	}
+	return mode
+}
+
+//----------
+
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// Vars of env not present (or with a different value) in base.
+func envDiff(base, env []string) []string {
+	m := map[string]bool{}
+	for _, s := range base {
+		m[s] = true
+	}
+	u := []string{}
+	for _, s := range env {
+		if !m[s] {
+			u = append(u, s)
+		}
+	}
+	return u
+}
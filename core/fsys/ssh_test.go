@@ -0,0 +1,157 @@
+// +build !windows
+
+package fsys
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Runs the remote cmd line locally in place of ssh.
+func setupSshStandIn(t *testing.T, dir string) func() {
+	t.Helper()
+	fn := filepath.Join(dir, "fakessh")
+	src := "#!/bin/sh\n# args: -- host cmdline\n[ \"$1\" = -- ] || exit 99\nshift 2\nexec sh -c \"$1\"\n"
+	if err := ioutil.WriteFile(fn, []byte(src), 0700); err != nil {
+		t.Fatal(err)
+	}
+	old := SshCmd
+	SshCmd = []string{fn}
+	return func() { SshCmd = old }
+}
+
+func TestSshFS1(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fsys")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer setupSshStandIn(t, dir)()
+
+	name := "ssh://host" + filepath.Join(dir, "a b.txt")
+
+	// not exist
+	if _, err := Stat(name); !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+	if _, err := ReadFile(name); !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+
+	// write/read
+	if err := WriteFile(name, []byte("it's\n"), 0640, false); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ReadFile(name)
+	if err != nil || string(b) != "it's\n" {
+		t.Fatal(string(b), err)
+	}
+	fi, err := Stat(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.IsDir() || fi.Size() != 5 || fi.Mode().Perm() != 0640 || fi.Name() != "a b.txt" {
+		t.Fatal(fi.IsDir(), fi.Size(), fi.Mode(), fi.Name())
+	}
+
+	// overwrite with backup, mode kept
+	if err := WriteFile(name, []byte("b"), 0644, true); err != nil {
+		t.Fatal(err)
+	}
+	b, _ = ReadFile(name)
+	b2, _ := ReadFile(name + "~")
+	if string(b) != "b" || string(b2) != "it's\n" {
+		t.Fatal(string(b), string(b2))
+	}
+	if fi, _ := Stat(name); fi.Mode().Perm() != 0640 {
+		t.Fatal(fi.Mode())
+	}
+
+	// dir
+	dname := "ssh://host" + dir
+	fi, err = Stat(dname)
+	if err != nil || !fi.IsDir() {
+		t.Fatal(fi, err)
+	}
+	fis, err := ReadDir(dname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := map[string]bool{}
+	for _, fi := range fis {
+		names[fi.Name()] = true
+	}
+	if len(fis) != 3 || !names["a b.txt"] || !names["a b.txt~"] || !names["fakessh"] {
+		t.Fatal(names)
+	}
+
+	// cmd
+	env := append(os.Environ(), "ED_TEST=1")
+	cmd := Command(context.Background(), dname, []string{"sh", "-c", "pwd; echo $ED_TEST"}, env)
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir2, _ := filepath.EvalSymlinks(dir)
+	if s := string(out); s != dir+"\n1\n" && s != dir2+"\n1\n" {
+		t.Fatalf("%q", s)
+	}
+}
+
+func TestPaths(t *testing.T) {
+	if s := Clean("ssh://host/a/../b//c"); s != "ssh://host/b/c" {
+		t.Fatal(s)
+	}
+	if s := Dir("ssh://host/a/b"); s != "ssh://host/a" {
+		t.Fatal(s)
+	}
+	if s := Join("ssh://host/a", "b", "c"); s != "ssh://host/a/b/c" {
+		t.Fatal(s)
+	}
+	if s := Dir("ssh://host/a"); s != "ssh://host/" {
+		t.Fatal(s)
+	}
+	if !IsAbs("ssh://host/a") || IsLocal("ssh://host/a") || !IsLocal("/a") {
+		t.Fatal()
+	}
+	// not read as ssh options
+	if !IsLocal("ssh://-oProxyCommand=x/a") || !IsLocal("ssh:///a") {
+		t.Fatal()
+	}
+}
+
+func TestSshHostDown(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fsys")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// counts the connection attempts
+	fn := filepath.Join(dir, "fakessh")
+	count := filepath.Join(dir, "count")
+	src := "#!/bin/sh\necho >> " + count + "\nexit 255\n"
+	if err := ioutil.WriteFile(fn, []byte(src), 0700); err != nil {
+		t.Fatal(err)
+	}
+	old := SshCmd
+	SshCmd = []string{fn}
+	defer func() { SshCmd = old }()
+
+	name := "ssh://downhost" + filepath.Join(dir, "a.txt")
+	for i := 0; i < 3; i++ {
+		if _, err := ReadFile(name); err == nil {
+			t.Fatal("expecting error")
+		}
+	}
+	b, err := ioutil.ReadFile(count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "\n" {
+		t.Fatalf("%q", b)
+	}
+}
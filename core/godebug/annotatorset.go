@@ -48,10 +48,15 @@ func NewAnnotatorSet() *AnnotatorSet {
 //----------
 
 func (annset *AnnotatorSet) AnnotateAstFile(astFile *ast.File, filename string, files *Files) error {
+	_, err := annset.annotateAstFile2(astFile, filename, files)
+	return err
+}
+
+func (annset *AnnotatorSet) annotateAstFile2(astFile *ast.File, filename string, files *Files) (*AnnotatedFileInfo, error) {
 
 	afd, err := annset.annotatorFileData(filename, files)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	ann := NewAnnotator(annset.FSet, files.NodeAnnType)
@@ -63,7 +68,7 @@ func (annset *AnnotatorSet) AnnotateAstFile(astFile *ast.File, filename string,
 	ann.AnnotateAstFile(astFile, typ)
 
 	// n debug stmts inserted
-	afd.DebugLen = ann.debugIndex
+	info := &AnnotatedFileInfo{DebugLen: ann.debugIndex}
 
 	// insert imports if debug stmts were inserted
 	if ann.builtDebugLineStmt {
@@ -73,21 +78,45 @@ func (annset *AnnotatorSet) AnnotateAstFile(astFile *ast.File, filename string,
 		annset.insertImport(astFile, "_", GodebugconfigPkgPath)
 
 		// insert exit in main
-		ok := annset.insertDebugExitInFunction(astFile, "main")
-		if !annset.InsertedExitIn.Main {
-			annset.InsertedExitIn.Main = ok
-		}
+		info.ExitInMain = annset.insertDebugExitInFunction(astFile, "main")
 
 		// insert exit in testmain
-		ok = annset.insertDebugExitInFunction(astFile, "TestMain")
-		if !annset.InsertedExitIn.TestMain {
-			annset.InsertedExitIn.TestMain = ok
-		}
+		info.ExitInTestMain = annset.insertDebugExitInFunction(astFile, "TestMain")
 
 		// keep test files package names in case of need to build testmain files
-		annset.keepTestPackage(filename, astFile)
+		if strings.HasSuffix(filename, "_test.go") {
+			info.TestPkgName = astFile.Name.Name
+		}
+	}
+
+	if err := annset.addAnnotatedFileInfo(filename, files, info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// Updates the annotator set state with the result of annotating a file. Also used when the annotated file is reused from a cache.
+func (annset *AnnotatorSet) addAnnotatedFileInfo(filename string, files *Files, info *AnnotatedFileInfo) error {
+	afd, err := annset.annotatorFileData(filename, files)
+	if err != nil {
+		return err
 	}
 
+	annset.afds.Lock()
+	defer annset.afds.Unlock()
+
+	afd.DebugLen = info.DebugLen
+	if info.ExitInMain {
+		annset.InsertedExitIn.Main = true
+	}
+	if info.ExitInTestMain {
+		annset.InsertedExitIn.TestMain = true
+	}
+	if info.TestPkgName != "" {
+		// keep one pkg name per dir
+		dir := filepath.Dir(filename)
+		annset.testFilesPkgs[dir] = info.TestPkgName
+	}
 	return nil
 }
 
@@ -169,6 +198,10 @@ func (annset *AnnotatorSet) ConfigContent() string {
 	if debug.SyncSend {
 		syncSendStr = "true"
 	}
+	structuredValuesStr := "false"
+	if debug.StructuredValues {
+		structuredValuesStr = "true"
+	}
 
 	src := `package godebugconfig
 import "` + DebugPkgPath + `"
@@ -176,6 +209,7 @@ func init(){
 	debug.ServerNetwork = "` + debug.ServerNetwork + `"
 	debug.ServerAddress = "` + debug.ServerAddress + `"
 	debug.SyncSend = ` + syncSendStr + `
+	debug.StructuredValues = ` + structuredValuesStr + `
 	debug.AnnotatorFilesData = []*debug.AnnotatorFileData{
 		` + entriesStr + `
 	}
@@ -241,13 +275,12 @@ func TestMain(m *testing.M) {
 
 //----------
 
-func (annset *AnnotatorSet) keepTestPackage(filename string, astFile *ast.File) {
-	isTest := strings.HasSuffix(filename, "_test.go")
-	if isTest {
-		// keep one pkg name per dir
-		dir := filepath.Dir(filename)
-		annset.testFilesPkgs[dir] = astFile.Name.Name // pkg name
-	}
+// Summary of the annotation of a file (allows to reuse a cached annotated file).
+type AnnotatedFileInfo struct {
+	DebugLen       int    // n debug stmts inserted
+	ExitInMain     bool   // inserted debug exit in main()
+	ExitInTestMain bool   // inserted debug exit in TestMain()
+	TestPkgName    string // test file pkg name (if annotated)
 }
 
 //----------
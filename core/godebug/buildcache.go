@@ -0,0 +1,276 @@
+package godebug
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jmigpin/editor/util/osutil"
+)
+
+// Increment to invalidate existing caches (ex: annotator changes that don't change the debug pkg).
+const buildCacheVersion = 1
+
+const buildCacheIndexName = "editor_godebug_cache.json"
+const buildCacheLockName = "editor_godebug_cache.lock"
+
+// Caches of other working dirs not used for this long are removed.
+const buildCacheMaxAge = 30 * 24 * time.Hour
+
+//----------
+
+// Persistent work dir that keeps the annotated files of previous runs. Files are only re-annotated if the source hash or the annotation type changed. Having a fixed dir also allows "go build" to reuse its own cache.
+type BuildCache struct {
+	Dir string
+
+	version string
+	index   map[string]*BuildCacheEntry // [filename] read from the dir
+	valid   map[string]*BuildCacheEntry // [filename] reusable in this run
+	next    struct {
+		sync.Mutex
+		index map[string]*BuildCacheEntry // [filename] to write
+	}
+}
+
+// Returns an error if the dir is in use by another process.
+func OpenBuildCache(dir string) (*BuildCache, error) {
+	bc := &BuildCache{Dir: dir}
+	bc.version = buildCacheVersionStr()
+	bc.index = map[string]*BuildCacheEntry{}
+	bc.valid = map[string]*BuildCacheEntry{}
+	bc.next.index = map[string]*BuildCacheEntry{}
+
+	if err := os.MkdirAll(dir, 0770); err != nil {
+		return nil, err
+	}
+	if err := bc.lock(); err != nil {
+		return nil, err
+	}
+	bc.readIndex()
+	return bc, nil
+}
+
+//----------
+
+func (bc *BuildCache) lock() error {
+	filename := filepath.Join(bc.Dir, buildCacheLockName)
+	pidStr := strconv.Itoa(os.Getpid())
+	for i := 0; i < 2; i++ {
+		f, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0660)
+		if err == nil {
+			_, err = f.WriteString(pidStr)
+			if err2 := f.Close(); err == nil {
+				err = err2
+			}
+			return err
+		}
+		if !os.IsExist(err) {
+			return err
+		}
+		// remove stale lock (process ended without cleanup)
+		b, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return err
+		}
+		pid, err := strconv.Atoi(string(b))
+		if err == nil && osutil.ProcessExists(pid) {
+			return fmt.Errorf("build cache in use by pid %v: %v", pid, bc.Dir)
+		}
+		if err := os.Remove(filename); err != nil {
+			return err
+		}
+	}
+	return fmt.Errorf("unable to lock build cache: %v", bc.Dir)
+}
+
+func (bc *BuildCache) Unlock() error {
+	return os.Remove(filepath.Join(bc.Dir, buildCacheLockName))
+}
+
+//----------
+
+func (bc *BuildCache) readIndex() {
+	filename := filepath.Join(bc.Dir, buildCacheIndexName)
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return // no cache
+	}
+	idx := &buildCacheIndex{}
+	if err := json.Unmarshal(b, idx); err != nil {
+		return // invalid cache
+	}
+	if idx.Version != bc.version {
+		return // old cache
+	}
+	bc.index = idx.Entries
+}
+
+func (bc *BuildCache) WriteIndex() error {
+	bc.next.Lock()
+	defer bc.next.Unlock()
+	idx := &buildCacheIndex{Version: bc.version, Entries: bc.next.index}
+	b, err := json.Marshal(idx)
+	if err != nil {
+		return err
+	}
+	filename := filepath.Join(bc.Dir, buildCacheIndexName)
+	return mkdirAllWriteFile(filename, b)
+}
+
+//----------
+
+// Removes all files from the dir that can't be reused. The remaining files are annotated files that are still valid (source hash, annotation type and file index didn't change).
+func (bc *BuildCache) Prune(cmd *Cmd, files *Files, fileIndexes map[string]int) error {
+	// annotated files (at tmp) that can be reused
+	reuse := map[string]string{} // [filenameAtTmp]filename
+	for filename := range files.annFilenames {
+		e, ok := bc.index[filename]
+		if !ok {
+			continue
+		}
+		fafd, ok := files.annFileData[filename]
+		if !ok {
+			continue
+		}
+		if !bytes.Equal(e.FileHash, fafd.FileHash) ||
+			e.AnnType != files.annTypes[filename] ||
+			e.FileIndex != fileIndexes[filename] {
+			continue
+		}
+		reuse[cmd.tmpDirBasedFilename(filename)] = filename
+	}
+
+	keep := map[string]bool{
+		filepath.Join(bc.Dir, buildCacheIndexName): true,
+		filepath.Join(bc.Dir, buildCacheLockName):  true,
+	}
+	err := filepath.Walk(bc.Dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || keep[path] {
+			return nil
+		}
+		if filename, ok := reuse[path]; ok {
+			bc.valid[filename] = bc.index[filename]
+			return nil
+		}
+		return os.Remove(path)
+	})
+	if err != nil {
+		return fmt.Errorf("buildcache: prune: %v", err)
+	}
+	return nil
+}
+
+//----------
+
+// Returns the annotation info if the annotated file is in the cache and can be reused.
+func (bc *BuildCache) Get(filename string) (*AnnotatedFileInfo, bool) {
+	e, ok := bc.valid[filename]
+	if !ok {
+		return nil, false
+	}
+	bc.add(filename, e)
+	return &e.Info, true
+}
+
+func (bc *BuildCache) Set(filename string, files *Files, fileIndex int, info *AnnotatedFileInfo) {
+	e := &BuildCacheEntry{
+		FileHash:  files.annFileData[filename].FileHash,
+		AnnType:   files.annTypes[filename],
+		FileIndex: fileIndex,
+		Info:      *info,
+	}
+	bc.add(filename, e)
+}
+
+func (bc *BuildCache) add(filename string, e *BuildCacheEntry) {
+	bc.next.Lock()
+	defer bc.next.Unlock()
+	bc.next.index[filename] = e
+}
+
+//----------
+
+type BuildCacheEntry struct {
+	FileHash  []byte
+	AnnType   AnnotationType
+	FileIndex int
+	Info      AnnotatedFileInfo
+}
+
+type buildCacheIndex struct {
+	Version string
+	Entries map[string]*BuildCacheEntry
+}
+
+//----------
+
+func buildCacheVersionStr() string {
+	// the debug pkg is compiled with the annotated files
+	buf := &bytes.Buffer{}
+	for _, fp := range DebugFilePacks() {
+		buf.WriteString(fp.Name)
+		buf.WriteString(fp.Data)
+	}
+	h := hex.EncodeToString(sourceHash(buf.Bytes()))
+	return fmt.Sprintf("%v_%v", buildCacheVersion, h)
+}
+
+// Removes the caches of other working dirs (siblings of dir) that were not used for maxAge. The dir modtime is updated when the cache is locked (lock file created).
+func pruneOldBuildCaches(dir string, maxAge time.Duration) error {
+	parent := filepath.Dir(dir)
+	fis, err := ioutil.ReadDir(parent)
+	if err != nil {
+		return err
+	}
+	for _, fi := range fis {
+		name := fi.Name()
+		if !fi.IsDir() || !(strings.HasPrefix(name, "mod_") || strings.HasPrefix(name, "gopath_")) {
+			continue
+		}
+		d := filepath.Join(parent, name)
+		if d == dir || time.Since(fi.ModTime()) < maxAge {
+			continue
+		}
+		if buildCacheInUse(d) {
+			continue
+		}
+		if err := os.RemoveAll(d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func buildCacheInUse(dir string) bool {
+	b, err := ioutil.ReadFile(filepath.Join(dir, buildCacheLockName))
+	if err != nil {
+		return false
+	}
+	pid, err := strconv.Atoi(string(b))
+	return err == nil && osutil.ProcessExists(pid)
+}
+
+// Fixed dir based on the working dir.
+func buildCacheDir(dir string, noModules bool) string {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		base = os.TempDir()
+	}
+	d := "mod"
+	if noModules {
+		d = "gopath"
+	}
+	h := hex.EncodeToString(sourceHash([]byte(dir)))
+	return filepath.Join(base, "editor_godebug", d+"_"+h)
+}
@@ -0,0 +1,137 @@
+package godebug
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestBuildCache1(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "editor_buildcache_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	cacheDir := filepath.Join(tmp, "cache")
+
+	f1 := filepath.Join(tmp, "src", "a.go")
+	f2 := filepath.Join(tmp, "src", "b.go")
+	newFiles := func(src1, src2 string) *Files {
+		files := NewFiles(nil, false)
+		for f, src := range map[string]string{f1: src1, f2: src2} {
+			files.annFilenames[f] = struct{}{}
+			files.annTypes[f] = AnnotationTypeFile
+			files.annFileData[f] = &AnnFileData{FileHash: sourceHash([]byte(src))}
+		}
+		return files
+	}
+	indexes := map[string]int{f1: 0, f2: 1}
+
+	// first run: nothing to reuse
+	cmd := NewCmd()
+	bc, err := OpenBuildCache(cacheDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd.tmpDir, cmd.buildCache = cacheDir, bc
+	files := newFiles("a1", "b1")
+	if err := bc.Prune(cmd, files, indexes); err != nil {
+		t.Fatal(err)
+	}
+	for f, i := range indexes {
+		if _, ok := bc.Get(f); ok {
+			t.Fatal("unexpected cached file")
+		}
+		if err := mkdirAllWriteFile(cmd.tmpDirBasedFilename(f), []byte("ann")); err != nil {
+			t.Fatal(err)
+		}
+		bc.Set(f, files, i, &AnnotatedFileInfo{DebugLen: 7})
+	}
+	stale := cmd.tmpDirBasedFilename(filepath.Join(tmp, "src", "go.mod"))
+	if err := mkdirAllWriteFile(stale, []byte("module a")); err != nil {
+		t.Fatal(err)
+	}
+	if err := bc.WriteIndex(); err != nil {
+		t.Fatal(err)
+	}
+
+	// locked while in use
+	if _, err := OpenBuildCache(cacheDir); err == nil {
+		t.Fatal("expecting lock error")
+	}
+	if err := bc.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+
+	// second run: b.go changed
+	bc, err = OpenBuildCache(cacheDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bc.Unlock()
+	cmd.buildCache = bc
+	files = newFiles("a1", "b2")
+	if err := bc.Prune(cmd, files, indexes); err != nil {
+		t.Fatal(err)
+	}
+	info, ok := bc.Get(f1)
+	if !ok || info.DebugLen != 7 {
+		t.Fatalf("expecting cached file: %v", info)
+	}
+	if _, ok := bc.Get(f2); ok {
+		t.Fatal("changed file should not be reused")
+	}
+	for _, f := range []string{cmd.tmpDirBasedFilename(f2), stale} {
+		if _, err := os.Stat(f); !os.IsNotExist(err) {
+			t.Fatalf("expecting file to be pruned: %v", f)
+		}
+	}
+}
+
+func TestBuildCachePruneOld(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "editor_buildcache_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	old := time.Now().Add(-2 * buildCacheMaxAge)
+	dirs := map[string]bool{ // dir -> kept
+		"mod_cur":    true,
+		"mod_new":    true,
+		"mod_old":    false,
+		"gopath_old": false,
+		"mod_inuse":  true,
+		"other":      true,
+	}
+	for d := range dirs {
+		d2 := filepath.Join(tmp, d)
+		if err := os.MkdirAll(d2, 0770); err != nil {
+			t.Fatal(err)
+		}
+		if d == "mod_inuse" {
+			pid := []byte(strconv.Itoa(os.Getpid()))
+			if err := ioutil.WriteFile(filepath.Join(d2, buildCacheLockName), pid, 0660); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if d != "mod_new" {
+			if err := os.Chtimes(d2, old, old); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	if err := pruneOldBuildCaches(filepath.Join(tmp, "mod_cur"), buildCacheMaxAge); err != nil {
+		t.Fatal(err)
+	}
+	for d, kept := range dirs {
+		_, err := os.Stat(filepath.Join(tmp, d))
+		if kept != (err == nil) {
+			t.Fatal(d, err)
+		}
+	}
+}
@@ -15,6 +15,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -27,16 +28,15 @@ import (
 
 type Cmd struct {
 	Client *Client
-	Dir    string // "" will use current dir
+	Dir    string   // "" will use current dir
+	Env    []string // nil will use current process env
 	Stdout io.Writer
 	Stderr io.Writer
 
 	tmpDir       string
 	tmpBuiltFile string // file built and exec'd
 
-	//FixedTmpDir    bool // re-use tmp dir to allow caching
-	//FixedTmpDirPid int
-	//noTmpCleanup   bool
+	buildCache *BuildCache // nil if not using a cache (tmpDir is the cache dir)
 
 	env       []string // set at start
 	annset    *AnnotatorSet
@@ -60,6 +60,7 @@ type Cmd struct {
 		verbose   bool
 		filename  string
 		work      bool
+		noCache   bool
 		output    string // ex: -o filename
 		toolExec  string // ex: "wine" will run "wine args..."
 		dirs      []string
@@ -67,6 +68,7 @@ type Cmd struct {
 		address   string   // build/connect
 		env       []string // build
 		syncSend  bool
+		valueTree bool
 		otherArgs []string
 		runArgs   []string
 	}
@@ -79,9 +81,6 @@ func NewCmd() *Cmd {
 		Stderr: os.Stderr,
 	}
 
-	//cmd.FixedTmpDir = true
-	//cmd.FixedTmpDirPid = 2
-
 	return cmd
 }
 
@@ -132,6 +131,7 @@ func (cmd *Cmd) Start(ctx context.Context, args []string) (done bool, _ error) {
 
 	if m.run || m.test || m.build {
 		debug.SyncSend = cmd.flags.syncSend
+		debug.StructuredValues = cmd.flags.valueTree
 		cmd.setupServerNetAddr()
 		err := cmd.initAndAnnotate(ctx)
 		if err != nil {
@@ -171,9 +171,6 @@ func (cmd *Cmd) initAndAnnotate(ctx context.Context) error {
 	// "files" not in cmd.* to allow early GC
 	files := NewFiles(cmd.annset.FSet, cmd.noModules)
 	files.Dir = cmd.Dir
-	//if cmd.FixedTmpDir {
-	//	files.TmpDir = cmd.tmpDir
-	//}
 
 	files.Add(cmd.flags.files...)
 	files.Add(cmd.flags.dirs...)
@@ -251,6 +248,16 @@ func (cmd *Cmd) initAndAnnotate2(ctx context.Context, files *Files, mainFilename
 		files.verbose(cmd)
 	}
 
+	// depends on: files.Do
+	fileIndexes := cmd.assignFileIndexes(files)
+
+	// remove what can't be reused from previous runs
+	if cmd.buildCache != nil {
+		if err := cmd.buildCache.Prune(cmd, files, fileIndexes); err != nil {
+			return err
+		}
+	}
+
 	// copy
 	for filename := range files.copyFilenames {
 		dst := cmd.tmpDirBasedFilename(filename)
@@ -269,6 +276,11 @@ func (cmd *Cmd) initAndAnnotate2(ctx context.Context, files *Files, mainFilename
 	if err := cmd.annotateFiles(ctx, files); err != nil {
 		return err
 	}
+	if cmd.buildCache != nil {
+		if err := cmd.buildCache.WriteIndex(); err != nil {
+			return err
+		}
+	}
 
 	// write config file after annotations
 	if err := cmd.writeGoDebugConfigFilesToTmpDir(ctx, files); err != nil {
@@ -473,8 +485,15 @@ func (cmd *Cmd) tmpDirBasedFilename(filename string) string {
 
 //------------
 
+func (cmd *Cmd) environ() []string {
+	if cmd.Env != nil {
+		return append([]string{}, cmd.Env...)
+	}
+	return os.Environ()
+}
+
 func (cmd *Cmd) detectEnviron() []string {
-	env := os.Environ()
+	env := cmd.environ()
 
 	env = osutil.SetEnvs(env, cmd.flags.env)
 
@@ -519,7 +538,7 @@ func (cmd *Cmd) goPathStr() (string, bool) {
 
 func (cmd *Cmd) detectNoModules() bool {
 	env := []string{}
-	env = osutil.SetEnvs(env, os.Environ())
+	env = osutil.SetEnvs(env, cmd.environ())
 	env = osutil.SetEnvs(env, cmd.flags.env)
 
 	v := osutil.GetEnv(env, "GO111MODULE")
@@ -555,10 +574,13 @@ func (cmd *Cmd) Cleanup() {
 		}
 	}
 
-	if cmd.flags.work {
+	if cmd.buildCache != nil {
+		// don't cleanup cache dir
+		if err := cmd.buildCache.Unlock(); err != nil {
+			cmd.Printf("cleanup err: %v\n", err)
+		}
+	} else if cmd.flags.work {
 		// don't cleanup work dir
-		//} else if cmd.noTmpCleanup {
-		//	// don't cleanup work dir
 	} else if cmd.tmpDir != "" {
 		if err := os.RemoveAll(cmd.tmpDir); err != nil {
 			cmd.Printf("cleanup err: %v\n", err)
@@ -687,12 +709,20 @@ func (cmd *Cmd) annotateFiles(ctx context.Context, files *Files) error {
 
 func (cmd *Cmd) annotateFile(ctx context.Context, files *Files, filename string) error {
 
+	// annotated file from a previous run
+	if cmd.buildCache != nil {
+		if info, ok := cmd.buildCache.Get(filename); ok {
+			return cmd.annset.addAnnotatedFileInfo(filename, files, info)
+		}
+	}
+
 	dst := cmd.tmpDirBasedFilename(filename)
 	astFile, err := files.fullAstFile(filename)
 	if err != nil {
 		return err
 	}
-	if err := cmd.annset.AnnotateAstFile(astFile, filename, files); err != nil {
+	info, err := cmd.annset.annotateAstFile2(astFile, filename, files)
+	if err != nil {
 		return err
 	}
 
@@ -704,28 +734,60 @@ func (cmd *Cmd) annotateFile(ctx context.Context, files *Files, filename string)
 	if err := cmd.mkdirAllWriteAstFile(dst, astFile); err != nil {
 		return err
 	}
+
+	if cmd.buildCache != nil {
+		afd, err := cmd.annset.annotatorFileData(filename, files)
+		if err != nil {
+			return err
+		}
+		cmd.buildCache.Set(filename, files, afd.FileIndex, info)
+	}
 	return nil
 }
 
+// Assigns the file indexes in sorted order to have the same indexes between runs (annotated files have the index builtin).
+func (cmd *Cmd) assignFileIndexes(files *Files) map[string]int {
+	u := []string{}
+	for filename := range files.annFilenames {
+		u = append(u, filename)
+	}
+	sort.Strings(u)
+	m := map[string]int{}
+	for _, filename := range u {
+		afd, err := cmd.annset.annotatorFileData(filename, files)
+		if err != nil {
+			continue // will fail later when annotating
+		}
+		m[filename] = afd.FileIndex
+	}
+	return m
+}
+
 //------------
 
 func (cmd *Cmd) setupTmpDir() (string, error) {
+	m := &cmd.flags.mode
+	if !cmd.flags.noCache && (m.run || m.test || m.build) {
+		// use a fixed directory to reuse annotated files and allow "go build" to use its cache
+		dir := buildCacheDir(cmd.Dir, cmd.noModules)
+		bc, err := OpenBuildCache(dir)
+		if err == nil {
+			cmd.buildCache = bc
+			if err := pruneOldBuildCaches(dir, buildCacheMaxAge); err != nil && cmd.flags.verbose {
+				cmd.Printf("buildcache: %v\n", err)
+			}
+			return dir, nil
+		}
+		// ex: another session is using the cache, continue without it
+		if cmd.flags.verbose {
+			cmd.Printf("buildcache: %v\n", err)
+		}
+	}
+
 	d := "editor_godebug_mod_work"
 	if cmd.noModules {
 		d = "editor_godebug_gopath_work"
 	}
-	//if cmd.FixedTmpDir {
-	//	// use a fixed directory to allow "go build" to use the cache
-	//	// there is only one godebug session per editor, so ok to use pid
-	//	cmd.noTmpCleanup = true
-	//	pid := os.Getpid()
-	//	if cmd.FixedTmpDirPid != 0 {
-	//		pid = cmd.FixedTmpDirPid
-	//	}
-	//	d += fmt.Sprintf("_pid%v", pid)
-	//	tmpDir := filepath.Join(os.TempDir(), d)
-	//	return tmpDir, nil
-	//}
 	return ioutil.TempDir(os.TempDir(), d)
 }
 
@@ -816,9 +878,11 @@ func (cmd *Cmd) parseRunArgs(args []string) (done bool, _ error) {
 	cmd.dirsFlag(f)
 	cmd.filesFlag(f)
 	cmd.workFlag(f)
+	cmd.noCacheFlag(f)
 	cmd.verboseFlag(f)
 	cmd.toolExecFlag(f)
 	cmd.syncSendFlag(f)
+	cmd.valueTreeFlag(f)
 	cmd.envFlag(f)
 
 	if err := f.Parse(args); err != nil {
@@ -844,11 +908,14 @@ func (cmd *Cmd) parseTestArgs(args []string) (done bool, _ error) {
 	cmd.dirsFlag(f)
 	cmd.filesFlag(f)
 	cmd.workFlag(f)
+	cmd.noCacheFlag(f)
 	cmd.verboseFlag(f)
 	cmd.toolExecFlag(f)
 	cmd.syncSendFlag(f)
+	cmd.valueTreeFlag(f)
 	cmd.envFlag(f)
 	run := f.String("run", "", "run test")
+	bench := f.String("bench", "", "run benchmark")
 	verboseTests := f.Bool("v", false, "verbose tests")
 
 	if err := f.Parse(args); err != nil {
@@ -866,6 +933,12 @@ func (cmd *Cmd) parseTestArgs(args []string) (done bool, _ error) {
 		cmd.flags.runArgs = append(a, cmd.flags.runArgs...)
 	}
 
+	// set test bench flag
+	if *bench != "" {
+		a := []string{"-test.bench", *bench}
+		cmd.flags.runArgs = append(a, cmd.flags.runArgs...)
+	}
+
 	// verbose
 	if *verboseTests {
 		a := []string{"-test.v"}
@@ -881,8 +954,10 @@ func (cmd *Cmd) parseBuildArgs(args []string) (done bool, _ error) {
 	cmd.dirsFlag(f)
 	cmd.filesFlag(f)
 	cmd.workFlag(f)
+	cmd.noCacheFlag(f)
 	cmd.verboseFlag(f)
 	cmd.syncSendFlag(f)
+	cmd.valueTreeFlag(f)
 	cmd.envFlag(f)
 	addr := f.String("addr", "", "address to serve from, built into the binary")
 	f.StringVar(&cmd.flags.output, "o", "", "output filename (default: ${filename}_godebug")
@@ -929,12 +1004,18 @@ func (cmd *Cmd) parseConnectArgs(args []string) (done bool, _ error) {
 func (cmd *Cmd) workFlag(fs *flag.FlagSet) {
 	fs.BoolVar(&cmd.flags.work, "work", false, "print workdir and don't cleanup on exit")
 }
+func (cmd *Cmd) noCacheFlag(fs *flag.FlagSet) {
+	fs.BoolVar(&cmd.flags.noCache, "nocache", false, "don't reuse annotated files from previous runs (uses a new workdir)")
+}
 func (cmd *Cmd) verboseFlag(fs *flag.FlagSet) {
 	fs.BoolVar(&cmd.flags.verbose, "verbose", false, "verbose godebug")
 }
 func (cmd *Cmd) syncSendFlag(fs *flag.FlagSet) {
 	fs.BoolVar(&cmd.flags.syncSend, "syncsend", false, "Don't send msgs in chunks (slow). Useful to get msgs before a crash.")
 }
+func (cmd *Cmd) valueTreeFlag(fs *flag.FlagSet) {
+	fs.BoolVar(&cmd.flags.valueTree, "valuetree", false, "Send structured values (depth limited) that can be expanded in the editor (slower, more memory).")
+}
 func (cmd *Cmd) toolExecFlag(fs *flag.FlagSet) {
 	fs.StringVar(&cmd.flags.toolExec, "toolexec", "", "execute cmd, useful to run a tool with the output file (ex: wine outputfilename")
 }
@@ -1005,6 +1086,7 @@ Examples:
 	GoDebug test -help
 	GoDebug test
 	GoDebug test -run mytest
+	GoDebug test -bench mybenchmark
 	GoDebug build -addr=:8080 main.go
 	GoDebug connect -addr=:8080
 `
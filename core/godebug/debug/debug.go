@@ -1,8 +1,11 @@
 package debug
 
 import (
+	"bytes"
 	"fmt"
 	"os"
+	"runtime"
+	"strconv"
 	"sync"
 )
 
@@ -47,6 +50,25 @@ func ExitServer() {
 // Auto-inserted at annotations. Not to be used.
 func Line(fileIndex, debugIndex, offset int, item Item) {
 	hotStartServer()
-	lmsg := &LineMsg{FileIndex: fileIndex, DebugIndex: debugIndex, Offset: offset, Item: item}
+	lmsg := &LineMsg{FileIndex: fileIndex, DebugIndex: debugIndex, Offset: offset, Item: item, GoId: goId()}
 	server.Send(lmsg)
 }
+
+//----------
+
+// Goroutine id. Allows the client to reconstruct the call stack of each goroutine.
+func goId() int {
+	var buf [64]byte
+	n := runtime.Stack(buf[:], false)
+	// ex: "goroutine 18 [running]:..."
+	b := bytes.TrimPrefix(buf[:n], []byte("goroutine "))
+	i := bytes.IndexByte(b, ' ')
+	if i < 0 {
+		return 0
+	}
+	v, err := strconv.Atoi(string(b[:i]))
+	if err != nil {
+		return 0
+	}
+	return v
+}
@@ -0,0 +1,15 @@
+package debug
+
+import "testing"
+
+func TestGoId(t *testing.T) {
+	id := goId()
+	if id <= 0 {
+		t.Fatalf("bad goroutine id: %v", id)
+	}
+	ch := make(chan int)
+	go func() { ch <- goId() }()
+	if id2 := <-ch; id2 <= 0 || id2 == id {
+		t.Fatalf("bad goroutine id: %v %v", id, id2)
+	}
+}
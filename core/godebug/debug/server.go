@@ -13,11 +13,12 @@ import (
 var AnnotatorFilesData []*AnnotatorFileData // all debug data
 var ServerNetwork string
 var ServerAddress string
-var SyncSend bool // don't send in chunks (usefull to get msgs before crash)
+var SyncSend bool         // don't send in chunks (usefull to get msgs before crash)
+var StructuredValues bool // send depth limited value trees (expandable values)
 
 //----------
 
-//var logger = log.New(os.Stdout, "debug: ", 0)
+// var logger = log.New(os.Stdout, "debug: ", 0)
 var logger = log.New(ioutil.Discard, "debug: ", 0)
 
 const chunkSendRate = 15       // per second
@@ -50,6 +50,7 @@ type LineMsg struct {
 	DebugIndex int
 	Offset     int
 	Item       Item
+	GoId       int // goroutine id
 }
 
 type FilesDataMsg struct {
@@ -69,7 +70,8 @@ type AnnotatorFileData struct {
 type Item interface {
 }
 type ItemValue struct {
-	Str string
+	Str  string
+	Tree *ValueTree // optional, see StructuredValues
 }
 type ItemList struct { // separated by ","
 	List []Item
@@ -146,7 +148,11 @@ type V interface{}
 
 // ItemValue
 func IV(v V) Item {
-	return &ItemValue{Str: stringifyV(v)}
+	iv := &ItemValue{Str: stringifyV(v)}
+	if StructuredValues {
+		iv.Tree = buildValueTree(v)
+	}
+	return iv
 }
 
 // ItemValue: raw string
@@ -0,0 +1,126 @@
+package debug
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// Depth limited structured value. Allows the client to drill into fields, map entries and slice elements instead of a single truncated string.
+type ValueTree struct {
+	Name   string // field name, map key or slice index
+	Str    string // short representation of the value
+	Childs []*ValueTree
+	More   bool // childs were cut due to limits
+}
+
+//----------
+
+const valueTreeMaxDepth = 5
+const valueTreeMaxChilds = 100
+const valueTreeMaxNodes = 1000 // total, keeps the cost bounded (runs in the debugged program)
+const valueTreeStrMax = 60
+
+func buildValueTree(v V) *ValueTree {
+	vt := &ValueTree{}
+	vtb := &valueTreeBuilder{maxDepth: valueTreeMaxDepth, maxChilds: valueTreeMaxChilds, nodes: valueTreeMaxNodes}
+	vtb.build(vt, reflect.ValueOf(v), 0)
+	if len(vt.Childs) == 0 {
+		return nil // not structured, the string value is enough
+	}
+	return vt
+}
+
+//----------
+
+type valueTreeBuilder struct {
+	maxDepth  int
+	maxChilds int
+	nodes     int // nodes left to add
+}
+
+func (vtb *valueTreeBuilder) build(vt *ValueTree, v reflect.Value, depth int) {
+	vt.Str = vtb.shortStr(v)
+	if !v.IsValid() || depth >= vtb.maxDepth {
+		return
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return
+		}
+		// avoid an extra level for pointers to composites
+		e := v.Elem()
+		vtb.build(vt, e, depth+1)
+		vt.Str = vtb.shortStr(v)
+	case reflect.Struct:
+		vt2 := v.Type()
+		for i := 0; i < v.NumField(); i++ {
+			if !vtb.addChild(vt, vt2.Field(i).Name, v.Field(i), depth) {
+				break
+			}
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			name := vtb.shortStr(iter.Key())
+			if !vtb.addChild(vt, name, iter.Value(), depth) {
+				break
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+			return // []byte: keep the string representation
+		}
+		for i := 0; i < v.Len(); i++ {
+			if !vtb.addChild(vt, strconv.Itoa(i), v.Index(i), depth) {
+				break
+			}
+		}
+	}
+}
+
+func (vtb *valueTreeBuilder) addChild(vt *ValueTree, name string, v reflect.Value, depth int) bool {
+	if len(vt.Childs) >= vtb.maxChilds || vtb.nodes <= 0 {
+		vt.More = true
+		return false
+	}
+	vtb.nodes--
+	c := &ValueTree{Name: name}
+	vt.Childs = append(vt.Childs, c)
+	vtb.build(c, v, depth+1)
+	return true
+}
+
+func (vtb *valueTreeBuilder) shortStr(v reflect.Value) string {
+	if !v.IsValid() {
+		return "nil"
+	}
+	if !v.CanInterface() {
+		// unexported field: print the underlying value
+		return vtb.unexportedStr(v)
+	}
+	p := NewPrint(valueTreeStrMax, 1)
+	return string(p.Do(v.Interface()))
+}
+
+func (vtb *valueTreeBuilder) unexportedStr(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64)
+	case reflect.String:
+		p := NewPrint(valueTreeStrMax, 1)
+		return string(p.Do(v.String()))
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice:
+		if v.IsNil() {
+			return "nil"
+		}
+	}
+	return fmt.Sprintf("(%v)", v.Type())
+}
@@ -0,0 +1,80 @@
+package debug
+
+import "testing"
+
+func TestValueTree1(t *testing.T) {
+	type St1 struct {
+		A int
+		b string
+		C map[string]int
+	}
+	v := &St1{A: 1, b: "x", C: map[string]int{"k": 2}}
+	vt := buildValueTree(v)
+	if vt == nil || len(vt.Childs) != 3 {
+		t.Fatalf("bad tree: %+v", vt)
+	}
+	if c := vt.Childs[0]; c.Name != "A" || c.Str != "1" {
+		t.Fatalf("bad child: %+v", c)
+	}
+	if c := vt.Childs[1]; c.Name != "b" || c.Str != `"x"` {
+		t.Fatalf("bad child: %+v", c)
+	}
+	c := vt.Childs[2]
+	if len(c.Childs) != 1 || c.Childs[0].Name != `"k"` || c.Childs[0].Str != "2" {
+		t.Fatalf("bad map child: %+v", c)
+	}
+}
+
+func TestValueTree2(t *testing.T) {
+	// not structured
+	if vt := buildValueTree(1); vt != nil {
+		t.Fatal(vt)
+	}
+	if vt := buildValueTree([]byte("abc")); vt != nil {
+		t.Fatal(vt)
+	}
+}
+
+func TestValueTree3(t *testing.T) {
+	// limit childs
+	a := make([]int, valueTreeMaxChilds+10)
+	vt := buildValueTree(a)
+	if len(vt.Childs) != valueTreeMaxChilds || !vt.More {
+		t.Fatalf("bad limit: %v %v", len(vt.Childs), vt.More)
+	}
+}
+
+func TestValueTree4(t *testing.T) {
+	// pointer cycle is depth limited
+	type St1 struct{ Next *St1 }
+	v := &St1{}
+	v.Next = v
+	vt := buildValueTree(v)
+	depth := 0
+	for u := vt; len(u.Childs) > 0; u = u.Childs[0] {
+		depth++
+	}
+	if depth == 0 || depth > valueTreeMaxDepth {
+		t.Fatalf("bad depth: %v", depth)
+	}
+}
+
+func TestValueTree5(t *testing.T) {
+	// limit total nodes
+	v := &[100][100][100]int{}
+	vt := buildValueTree(v)
+	n := 0
+	var count func(vt *ValueTree) bool
+	count = func(vt *ValueTree) bool {
+		more := vt.More
+		for _, c := range vt.Childs {
+			n++
+			more = count(c) || more
+		}
+		return more
+	}
+	more := count(vt)
+	if n > valueTreeMaxNodes || !more {
+		t.Fatal(n, more)
+	}
+}
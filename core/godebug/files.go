@@ -24,7 +24,6 @@ import (
 // Finds the set of files that need to be annotated/copied.
 type Files struct {
 	Dir string
-	//TmpDir string
 
 	filenames       map[string]struct{}       // filenames to solve
 	progFilenames   map[string]struct{}       // program filenames (loaded)
@@ -147,9 +146,6 @@ func (files *Files) Do(ctx context.Context, mainFilename string, tests bool, env
 	if err := files.doAnnFilesHashes(); err != nil {
 		return err
 	}
-	//if err := files.reviewTmpDirCache(); err != nil {
-	//	return err
-	//}
 	return nil
 }
 
@@ -650,87 +646,6 @@ func (files *Files) NodeAnnType(n ast.Node) AnnotationType {
 	return AnnotationTypeNone
 }
 
-//----------
-
-//func (files *Files) reviewTmpDirCache() error {
-//	// TODO: in one rune a package was annotated, but in another run, the package is not required to be annotated, but the annotated files are in the cache and going to be used
-//	// TODO: in progfilenames and annotated, if in cache could have been just copied?
-//	// TODO: in progfilenames and not annotated, if in cache could have been annotated?
-
-//	if files.TmpDir == "" {
-//		return nil
-//	}
-
-//	fileAtTmp := func(f string) string {
-//		return filepath.Join(files.TmpDir, f)
-//	}
-
-//	// cache version (allows to invalidate the cache for update reasons)
-//	version := "1"
-//	versionFile := fileAtTmp("version")
-//	cacheValid, create := false, false
-//	b, err := ioutil.ReadFile(versionFile)
-//	if os.IsNotExist(err) {
-//		create = true
-//	} else {
-//		v := string(b)
-//		if v == version {
-//			cacheValid = true
-//		}
-//	}
-//	if !cacheValid {
-//		os.RemoveAll(files.TmpDir)
-//	}
-//	if create {
-//		if err := iout.MkdirAllWriteFile(versionFile, []byte(version), 0660); err != nil {
-//			return err
-//		}
-//	}
-
-//	// merge maps keys (filenames to check)
-//	m := map[string]struct{}{}
-//	for k := range files.annFilenames {
-//		m[k] = struct{}{}
-//	}
-//	for k := range files.copyFilenames {
-//		m[k] = struct{}{}
-//	}
-//	for k := range files.modFilenames {
-//		m[k] = struct{}{}
-//	}
-//	for k := range files.modMissings {
-//		m[k] = struct{}{}
-//	}
-
-//	// compare cache files with original files
-//	for f1 := range m {
-//		// cached
-//		f2 := fileAtTmp(f1)
-//		fi2, err := os.Stat(f2)
-//		if err != nil {
-//			continue // cached file missing
-//		}
-//		// original
-//		fi1, err := os.Stat(f1)
-//		if err != nil {
-//			// original file missing (ex: a go.mod)
-//			_ = os.Remove(f2) // remove from cache
-//			continue
-//		}
-
-//		cacheIsOld := fi2.ModTime().Before(fi1.ModTime())
-//		if cacheIsOld {
-//			_ = os.Remove(f2)
-//			continue
-//		}
-
-//		delete(files.copyFilenames, f1)
-//		delete(files.annFilenames, f1)
-//		//fmt.Printf("using cached: %v\n", f1)
-//	}
-//	return nil
-//}
-
 //----------
 //----------
 //----------
@@ -0,0 +1,208 @@
+package godebug
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+	"unicode"
+
+	"github.com/jmigpin/editor/core/godebug/debug"
+)
+
+// Matches the items of the line msgs with the source expressions of the annotated file, allowing to get the value of an expression by its source string.
+type ItemExprs struct {
+	tfile *token.File
+	src   []byte
+	nodes map[int][]ast.Node // offset -> nodes annotated at offset
+}
+
+func NewItemExprs(filename string, src []byte) (*ItemExprs, error) {
+	fset := token.NewFileSet()
+	astFile, err := parser.ParseFile(fset, filename, src, 0)
+	if err != nil {
+		return nil, err
+	}
+	ie := &ItemExprs{src: src}
+	ie.tfile = fset.File(astFile.Pos())
+	ie.nodes = map[int][]ast.Node{}
+
+	// index the nodes by the offsets used by the annotator
+	add := func(pos token.Pos, n ast.Node) {
+		o := ie.tfile.Offset(pos)
+		ie.nodes[o] = append(ie.nodes[o], n)
+	}
+	ast.Inspect(astFile, func(n ast.Node) bool {
+		switch t := n.(type) {
+		case *ast.ExprStmt, *ast.AssignStmt, *ast.ReturnStmt,
+			*ast.IncDecStmt, *ast.SendStmt:
+			add(t.End(), t)
+		case *ast.CallExpr:
+			add(t.Rparen, t)
+		}
+		return true
+	})
+	return ie, nil
+}
+
+//----------
+
+// Returns the values of the expressions in the line msg (normalized expression string -> value string).
+func (ie *ItemExprs) Values(lm *debug.LineMsg) map[string]string {
+	m := map[string]string{}
+	for _, n := range ie.nodes[lm.Offset] {
+		ie.matchNode(m, n, lm.Item)
+	}
+	return m
+}
+
+func (ie *ItemExprs) matchNode(m map[string]string, n ast.Node, item debug.Item) {
+	switch t := n.(type) {
+	case *ast.ExprStmt:
+		ie.match(m, t.X, item)
+	case *ast.AssignStmt:
+		switch t2 := item.(type) {
+		case *debug.ItemAssign:
+			ie.matchList(m, t.Lhs, t2.Lhs)
+			ie.matchList(m, t.Rhs, t2.Rhs)
+		case *debug.ItemList:
+			ie.matchList(m, t.Rhs, t2)
+		}
+	case *ast.ReturnStmt:
+		if t2, ok := item.(*debug.ItemList); ok {
+			ie.matchList(m, t.Results, t2)
+		}
+	case *ast.IncDecStmt:
+		if t2, ok := item.(*debug.ItemAssign); ok {
+			ie.matchList(m, []ast.Expr{t.X}, t2.Lhs)
+		}
+	case *ast.SendStmt:
+		if t2, ok := item.(*debug.ItemSend); ok {
+			ie.match(m, t.Chan, t2.Chan)
+			ie.match(m, t.Value, t2.Value)
+		}
+	case *ast.CallExpr:
+		if t2, ok := item.(*debug.ItemCallEnter); ok {
+			ie.matchList(m, t.Args, t2.Args)
+		}
+	}
+}
+
+func (ie *ItemExprs) matchList(m map[string]string, exprs []ast.Expr, list *debug.ItemList) {
+	if list == nil || len(exprs) != len(list.List) {
+		return
+	}
+	for i, e := range exprs {
+		ie.match(m, e, list.List[i])
+	}
+}
+
+func (ie *ItemExprs) match(m map[string]string, e ast.Expr, item debug.Item) {
+	if item == nil {
+		return
+	}
+	switch t := e.(type) {
+	case *ast.Ident, *ast.BasicLit:
+		if _, ok := item.(*debug.ItemValue); ok {
+			ie.set(m, e, item)
+		}
+	case *ast.SelectorExpr:
+		switch t2 := item.(type) {
+		case *debug.ItemValue:
+			ie.set(m, e, item)
+		case *debug.ItemSelector:
+			ie.match(m, t.X, t2.X)
+			ie.set(m, e, t2.Sel)
+		}
+	case *ast.CallExpr:
+		if t2, ok := item.(*debug.ItemCall); ok {
+			ie.set(m, e, t2.Result)
+			if t2.Args != nil && len(t.Args) == len(t2.Args.List) {
+				for i, a := range t.Args {
+					if i == 0 && isNewOrMake(t) {
+						continue // arg is a type
+					}
+					ie.match(m, a, t2.Args.List[i])
+				}
+			}
+		}
+	case *ast.BinaryExpr:
+		if t2, ok := item.(*debug.ItemBinary); ok {
+			ie.set(m, e, t2.Result)
+			ie.match(m, t.X, t2.X)
+			ie.match(m, t.Y, t2.Y)
+		}
+	case *ast.UnaryExpr:
+		if t2, ok := item.(*debug.ItemUnary); ok {
+			ie.set(m, e, t2.Result)
+			ie.match(m, t.X, t2.X)
+		}
+	case *ast.StarExpr:
+		if t2, ok := item.(*debug.ItemUnary); ok {
+			ie.set(m, e, t2.Result)
+			ie.match(m, t.X, t2.X)
+		}
+	case *ast.ParenExpr:
+		if t2, ok := item.(*debug.ItemParen); ok {
+			ie.match(m, t.X, t2.X)
+		}
+	case *ast.IndexExpr:
+		if t2, ok := item.(*debug.ItemIndex); ok {
+			ie.set(m, e, t2.Result)
+			ie.match(m, t.X, t2.Expr)
+			ie.match(m, t.Index, t2.Index)
+		}
+	case *ast.SliceExpr:
+		if t2, ok := item.(*debug.ItemIndex2); ok {
+			ie.set(m, e, t2.Result)
+			ie.match(m, t.X, t2.Expr)
+		}
+	case *ast.CompositeLit:
+		if t2, ok := item.(*debug.ItemLiteral); ok && t2.Fields != nil {
+			if len(t.Elts) == len(t2.Fields.List) {
+				for i, a := range t.Elts {
+					ie.match(m, a, t2.Fields.List[i])
+				}
+			}
+		}
+	case *ast.KeyValueExpr:
+		if t2, ok := item.(*debug.ItemKeyValue); ok {
+			ie.match(m, t.Value, t2.Value)
+		}
+	case *ast.TypeAssertExpr:
+		if t.Type != nil {
+			ie.match(m, t.X, item)
+		}
+	}
+}
+
+func (ie *ItemExprs) set(m map[string]string, e ast.Expr, item debug.Item) {
+	if item == nil {
+		return
+	}
+	s := ie.exprStr(e)
+	m[s] = StringifyItemFull(item)
+}
+
+func (ie *ItemExprs) exprStr(e ast.Expr) string {
+	s := ie.tfile.Offset(e.Pos())
+	t := ie.tfile.Offset(e.End())
+	return NormalizeExprStr(string(ie.src[s:t]))
+}
+
+//----------
+
+// Removes spaces to be able to compare expressions.
+func NormalizeExprStr(s string) string {
+	return strings.Map(func(ru rune) rune {
+		if unicode.IsSpace(ru) {
+			return -1
+		}
+		return ru
+	}, s)
+}
+
+func isNewOrMake(ce *ast.CallExpr) bool {
+	id, ok := ce.Fun.(*ast.Ident)
+	return ok && (id.Name == "new" || id.Name == "make")
+}
@@ -0,0 +1,53 @@
+package godebug
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jmigpin/editor/core/godebug/debug"
+)
+
+func TestItemExprs1(t *testing.T) {
+	src := `package main
+func main() {
+	n := len(buf)
+	req.Header = h
+}
+`
+	ie, err := NewItemExprs("main.go", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// n := len(buf)
+	o1 := strings.Index(src, "len(buf)") + len("len(buf)")
+	item1 := debug.IA(
+		debug.IL(debug.IVs("3")),
+		debug.IL(debug.IC("len", debug.IVs("3"), debug.IVs("[1 2 3]"))),
+	)
+	m := ie.Values(&debug.LineMsg{Offset: o1, Item: item1})
+	testItemExprsValue(t, m, "n", "3")
+	testItemExprsValue(t, m, "len( buf )", "3")
+	testItemExprsValue(t, m, "buf", "[1 2 3]")
+
+	// req.Header = h
+	o2 := strings.Index(src, "= h") + len("= h")
+	item2 := debug.IA(
+		debug.IL(debug.IVs("map[a:b]")),
+		debug.IL(debug.IVs("map[a:b]")),
+	)
+	m = ie.Values(&debug.LineMsg{Offset: o2, Item: item2})
+	testItemExprsValue(t, m, "req.Header", "map[a:b]")
+	testItemExprsValue(t, m, "h", "map[a:b]")
+}
+
+func testItemExprsValue(t *testing.T, m map[string]string, expr, v string) {
+	t.Helper()
+	u, ok := m[NormalizeExprStr(expr)]
+	if !ok {
+		t.Fatalf("expr not found: %q (%v)", expr, m)
+	}
+	if u != v {
+		t.Fatalf("expr %q: expected %q, got %q", expr, v, u)
+	}
+}
@@ -0,0 +1,88 @@
+package godebug
+
+import "github.com/jmigpin/editor/core/godebug/debug"
+
+// Visits the item and all inner items (depth first). Stops visiting the childs of an item if fn returns false.
+func WalkItem(item debug.Item, fn func(debug.Item) bool) {
+	if item == nil {
+		return
+	}
+	if !fn(item) {
+		return
+	}
+	w := func(u debug.Item) {
+		WalkItem(u, fn)
+	}
+	wl := func(u *debug.ItemList) {
+		if u != nil {
+			for _, e := range u.List {
+				w(e)
+			}
+		}
+	}
+	switch t := item.(type) {
+	case *debug.ItemList:
+		wl(t)
+	case *debug.ItemList2:
+		for _, e := range t.List {
+			w(e)
+		}
+	case *debug.ItemAssign:
+		wl(t.Lhs)
+		wl(t.Rhs)
+	case *debug.ItemSend:
+		w(t.Chan)
+		w(t.Value)
+	case *debug.ItemCall:
+		wl(t.Args)
+		w(t.Result)
+	case *debug.ItemCallEnter:
+		wl(t.Args)
+	case *debug.ItemIndex:
+		w(t.Result)
+		w(t.Expr)
+		w(t.Index)
+	case *debug.ItemIndex2:
+		w(t.Result)
+		w(t.Expr)
+		w(t.Low)
+		w(t.High)
+		w(t.Max)
+	case *debug.ItemKeyValue:
+		w(t.Key)
+		w(t.Value)
+	case *debug.ItemSelector:
+		w(t.X)
+		w(t.Sel)
+	case *debug.ItemTypeAssert:
+		w(t.X)
+		w(t.Type)
+	case *debug.ItemBinary:
+		w(t.Result)
+		w(t.X)
+		w(t.Y)
+	case *debug.ItemUnary:
+		w(t.Result)
+		w(t.X)
+	case *debug.ItemUnaryEnter:
+		w(t.X)
+	case *debug.ItemParen:
+		w(t.X)
+	case *debug.ItemLiteral:
+		wl(t.Fields)
+	}
+}
+
+//----------
+
+// Value trees present in the item (see debug.StructuredValues).
+func ItemValueTrees(item debug.Item) []*debug.ValueTree {
+	u := []*debug.ValueTree{}
+	WalkItem(item, func(item debug.Item) bool {
+		if iv, ok := item.(*debug.ItemValue); ok && iv.Tree != nil {
+			u = append(u, iv.Tree)
+		}
+		return true
+	})
+	return u
+}
@@ -0,0 +1,105 @@
+package godebug
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Finds the enclosing test/benchmark function (and "t.Run" subtests) at the offset and returns a regexp to select it in the "-run" (or "-bench") test flag.
+func TestRegexpAtOffset(filename string, src []byte, offset int) (re string, isBench bool, _ error) {
+	fset := token.NewFileSet()
+	astFile, err := parser.ParseFile(fset, filename, src, 0)
+	if err != nil {
+		return "", false, err
+	}
+	tf := fset.File(astFile.Pos())
+	if offset < 0 || offset > tf.Size() {
+		return "", false, fmt.Errorf("bad offset: %v", offset)
+	}
+	pos := tf.Pos(offset)
+	contains := func(n ast.Node) bool {
+		return n.Pos() <= pos && pos <= n.End()
+	}
+
+	// enclosing test function
+	var fd *ast.FuncDecl
+	for _, d := range astFile.Decls {
+		if u, ok := d.(*ast.FuncDecl); ok && contains(u) {
+			fd = u
+			break
+		}
+	}
+	if fd == nil || fd.Recv != nil || fd.Body == nil {
+		return "", false, fmt.Errorf("not inside a test function")
+	}
+	name := fd.Name.Name
+	switch {
+	case isTestFuncName(name, "Test"):
+	case isTestFuncName(name, "Benchmark"):
+		isBench = true
+	default:
+		return "", false, fmt.Errorf("not a test function: %v", name)
+	}
+
+	names := []string{name}
+
+	// enclosing subtests: t.Run("name", func(...){...})
+	ast.Inspect(fd.Body, func(n ast.Node) bool {
+		if n == nil || !contains(n) {
+			return false
+		}
+		ce, ok := n.(*ast.CallExpr)
+		if !ok || len(ce.Args) != 2 {
+			return true
+		}
+		se, ok := ce.Fun.(*ast.SelectorExpr)
+		if !ok || se.Sel.Name != "Run" {
+			return true
+		}
+		fl, ok := ce.Args[1].(*ast.FuncLit)
+		if !ok || !contains(fl) {
+			return true
+		}
+		bl, ok := ce.Args[0].(*ast.BasicLit)
+		if !ok || bl.Kind != token.STRING {
+			return false // dynamic name, stop at the parent test
+		}
+		s, err := strconv.Unquote(bl.Value)
+		if err != nil {
+			return false
+		}
+		names = append(names, rewriteSubtestName(s))
+		return true
+	})
+
+	// build regexp
+	u := []string{}
+	for _, s := range names {
+		u = append(u, "^"+regexp.QuoteMeta(s)+"$")
+	}
+	return strings.Join(u, "/"), isBench, nil
+}
+
+func isTestFuncName(name, prefix string) bool {
+	if !strings.HasPrefix(name, prefix) {
+		return false
+	}
+	// ex: "Testing" is not a test function
+	rest := name[len(prefix):]
+	return rest == "" || !(rest[0] >= 'a' && rest[0] <= 'z')
+}
+
+// Same as the testing pkg: spaces are replaced with underscores.
+func rewriteSubtestName(s string) string {
+	return strings.Map(func(ru rune) rune {
+		if ru == ' ' || ru == '\t' || ru == '\n' {
+			return '_'
+		}
+		return ru
+	}, s)
+}
@@ -0,0 +1,49 @@
+package godebug
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTestRegexpAtOffset1(t *testing.T) {
+	src := `package pkg1
+import "testing"
+func TestAbc(t *testing.T) {
+	a := 1
+	t.Run("sub one", func(t *testing.T) {
+		b := 2
+		t.Run("x.y", func(t *testing.T) {
+			c := 3
+		})
+	})
+}
+func BenchmarkDef(b *testing.B) {
+	d := 4
+}
+func helper() {
+	e := 5
+}
+`
+	testTestRegexp(t, src, "a := 1", `^TestAbc$`, false)
+	testTestRegexp(t, src, "b := 2", `^TestAbc$/^sub_one$`, false)
+	testTestRegexp(t, src, "c := 3", `^TestAbc$/^sub_one$/^x\.y$`, false)
+	testTestRegexp(t, src, "d := 4", `^BenchmarkDef$`, true)
+
+	// not in a test
+	o := strings.Index(src, "e := 5")
+	if _, _, err := TestRegexpAtOffset("a_test.go", []byte(src), o); err == nil {
+		t.Fatal("expecting error")
+	}
+}
+
+func testTestRegexp(t *testing.T, src, at, ere string, ebench bool) {
+	t.Helper()
+	o := strings.Index(src, at)
+	re, bench, err := TestRegexpAtOffset("a_test.go", []byte(src), o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if re != ere || bench != ebench {
+		t.Fatalf("expected %q (bench=%v), got %q (bench=%v)", ere, ebench, re, bench)
+	}
+}
@@ -31,6 +31,10 @@ type GoDebugInstance struct {
 	}
 	cancel context.CancelFunc
 	ready  sync.Mutex
+
+	valuesView *GDValuesView // ui goroutine only
+	watches    []string      // ui goroutine only
+	watchesN   int           // ignores outdated watch values (ui goroutine only)
 }
 
 func NewGoDebugInstance(ed *Editor) *GoDebugInstance {
@@ -75,6 +79,8 @@ func (gdi *GoDebugInstance) CancelAndClear() {
 	}
 	gdi.data.dataIndex = nil
 	gdi.clearInfosUI()
+	gdi.updateWatchERow(nil)
+	gdi.updateStackERow(nil)
 	gdi.dataUnlock()
 
 	gdi.cancel()
@@ -203,6 +209,15 @@ func (gdi *GoDebugInstance) printIndex(erow *ERow, annIndex, offset int) {
 	// output
 	//s := godebug.StringifyItemOffset(msg.DLine.Item, offset) // inner item
 	s := godebug.StringifyItemFull(msg.DLine.Item) // full item
+
+	// structured values (available if the program was annotated with value trees)
+	trees := godebug.ItemValueTrees(msg.DLine.Item)
+	if len(trees) > 0 {
+		vv := NewGDValuesView("annotation: "+s, trees)
+		gdi.showValuesView(vv)
+		return
+	}
+
 	gdi.ed.Messagef("annotation:\n\t%v\n", s)
 }
 
@@ -262,10 +277,18 @@ func (gdi *GoDebugInstance) Start(erow *ERow, args []string) error {
 		return fmt.Errorf("can't run on this erow type")
 	}
 
+	erow.Exec.setRerun(args, func() {
+		if err := gdi.Start(erow, args); err != nil {
+			gdi.ed.Error(err)
+		}
+	})
+
 	// only one instance at a time
 	gdi.CancelAndClear() // cancel previous run
 
 	erow.Exec.Start(func(ctx context.Context, w io.Writer) error {
+		erow.Exec.setJobCmd(ctx, nil, strings.Join(args, " "))
+
 		// wait for previous run to finish
 		gdi.ready.Lock()
 		defer gdi.ready.Unlock()
@@ -293,11 +316,36 @@ func (gdi *GoDebugInstance) Start(erow *ERow, args []string) error {
 	return nil
 }
 
+// Starts a test session with the test/benchmark (or subtest) at the text cursor.
+func (gdi *GoDebugInstance) StartTestHere(erow *ERow, args []string) error {
+	if !erow.Info.IsFileButNotDir() || !strings.HasSuffix(erow.Info.Name(), "_test.go") {
+		return fmt.Errorf("not a test file")
+	}
+	ta := erow.Row.TextArea
+	src, err := ta.Bytes()
+	if err != nil {
+		return err
+	}
+	re, isBench, err := godebug.TestRegexpAtOffset(erow.Info.Name(), src, ta.TextCursor.Index())
+	if err != nil {
+		return err
+	}
+	a := []string{"GoDebug", "test"}
+	if isBench {
+		a = append(a, "-run", "^$", "-bench", re)
+	} else {
+		a = append(a, "-run", re)
+	}
+	a = append(a, args...)
+	return gdi.Start(erow, a)
+}
+
 func (gdi *GoDebugInstance) start2(erow *ERow, args []string, ctx context.Context, w io.Writer) error {
 	cmd := godebug.NewCmd()
 	defer cmd.Cleanup()
 
 	cmd.Dir = erow.Info.Name()
+	cmd.Env = erow.Environ()
 	cmd.Stdout = w
 	cmd.Stderr = w
 
@@ -473,6 +521,8 @@ func (gdi *GoDebugInstance) updateUI2() {
 	for _, info := range gdi.ed.ERowInfos() {
 		gdi.updateInfoUI(info)
 	}
+	gdi.updateWatchERow(gdi.data.dataIndex)
+	gdi.updateStackERow(gdi.data.dataIndex)
 }
 
 func (gdi *GoDebugInstance) updateInfoUI(info *ERowInfo) {
@@ -563,11 +613,24 @@ type GDDataIndex struct {
 
 	Afds  []*debug.AnnotatorFileData // file index -> file afd
 	Files []*GDFileMsgs              // file index -> file msgs
+
+	// written with only the data read lock (watch values)
+	cache struct {
+		sync.Mutex
+		itemExprs map[int]*godebug.ItemExprs // file index -> source exprs (lazy)
+		fileSrcs  map[int][]byte             // file index -> source (lazy)
+	}
+
+	Msgs   []*GDLineMsg     // arrival index -> msg
+	stacks map[int]*GDFrame // goroutine id -> top frame
 }
 
 func NewGDDataIndex(ed *Editor) *GDDataIndex {
 	di := &GDDataIndex{ed: ed}
 	di.filesIndexM = map[string]int{}
+	di.cache.itemExprs = map[int]*godebug.ItemExprs{}
+	di.cache.fileSrcs = map[int][]byte{}
+	di.stacks = map[int]*GDFrame{}
 	return di
 }
 
@@ -591,6 +654,8 @@ func (di *GDDataIndex) clearMsgs() {
 	}
 	di.GlobalArrivalIndex = 0
 	di.SelectedArrivalIndex = 0
+	di.Msgs = nil
+	di.stacks = map[int]*GDFrame{}
 }
 
 //----------
@@ -623,6 +688,8 @@ func (di *GDDataIndex) handleFilesDataMsg(fdm *debug.FilesDataMsg) error {
 		name := di.FilesIndexKey(afd.Filename)
 		di.filesIndexM[name] = afd.FileIndex
 	}
+	di.cache.itemExprs = map[int]*godebug.ItemExprs{}
+	di.cache.fileSrcs = map[int][]byte{}
 	// init index
 	di.Files = make([]*GDFileMsgs, len(di.Afds))
 	for _, afd := range di.Afds {
@@ -651,6 +718,8 @@ func (di *GDDataIndex) handleLineMsg(u *debug.LineMsg) error {
 	// index msg
 	w := &di.Files[u.FileIndex].Lines[u.DebugIndex].Msgs
 	*w = append(*w, lm)
+	di.Msgs = append(di.Msgs, lm)
+	di.updateStack(lm)
 
 	// auto update selected index if at last position
 	if di.SelectedArrivalIndex == di.GlobalArrivalIndex-1 {
@@ -733,6 +802,8 @@ type GDLineMsg struct {
 	DLine              *debug.LineMsg
 	itemBytes          []byte
 	cachedAnn          *drawer4.Annotation
+	cachedExprValues   map[string]string // guarded by the data index cache lock
+	Frame              *GDFrame          // function frame where the msg was sent (nil at the goroutine start)
 }
 
 func (msg *GDLineMsg) build() *drawer4.Annotation {
@@ -0,0 +1,198 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/jmigpin/editor/core/godebug"
+	"github.com/jmigpin/editor/core/godebug/debug"
+	"github.com/jmigpin/editor/core/parseutil"
+	"github.com/jmigpin/editor/util/iout/iorw"
+)
+
+const goDebugStackRowName = "+GoDebugStack"
+
+//----------
+
+// Function call frame reconstructed from the msgs stream (call enter/result items). Frames are immutable to allow msgs to keep a reference to the stack at the time they arrived.
+type GDFrame struct {
+	Parent *GDFrame
+	Name   string
+	Enter  *GDLineMsg // call enter msg (sent at the caller)
+	Depth  int
+}
+
+func (f *GDFrame) depth() int {
+	if f == nil {
+		return 0
+	}
+	return f.Depth
+}
+
+//----------
+
+func (di *GDDataIndex) updateStack(lm *GDLineMsg) {
+	goId := lm.DLine.GoId
+	top := di.stacks[goId]
+
+	// pop frames of calls that have returned (the result is present)
+	godebug.WalkItem(lm.DLine.Item, func(item debug.Item) bool {
+		if ic, ok := item.(*debug.ItemCall); ok {
+			top = popGDFrame(top, ic.Name)
+			return false // inner calls were popped before
+		}
+		return true
+	})
+
+	lm.Frame = top
+
+	// push frame on call enter
+	if ice, ok := lm.DLine.Item.(*debug.ItemCallEnter); ok {
+		top = &GDFrame{Parent: top, Name: ice.Name, Enter: lm, Depth: top.depth() + 1}
+	}
+
+	di.stacks[goId] = top
+}
+
+// Removes the nearest frame with the name. Frames above it are rebuilt since frames are immutable (ex: "a:=f()+g()" has the "f" result only after entering "g").
+func popGDFrame(top *GDFrame, name string) *GDFrame {
+	var above []*GDFrame
+	for f := top; f != nil; f = f.Parent {
+		if f.Name != name {
+			above = append(above, f)
+			continue
+		}
+		// rebuild frames above
+		p := f.Parent
+		for i := len(above) - 1; i >= 0; i-- {
+			u := *above[i]
+			u.Parent = p
+			u.Depth = p.depth() + 1
+			p = &u
+		}
+		return p
+	}
+	return top // not found
+}
+
+//----------
+
+// Selects the next msg in the same goroutine that is not inside a nested call (over), or that is in a caller (out).
+func (gdi *GoDebugInstance) Step(erow *ERow, out bool) error {
+	if !gdi.dataLock() {
+		return fmt.Errorf("no godebug session")
+	}
+	ok := gdi.step(out)
+	gdi.dataUnlock()
+	if !ok {
+		return fmt.Errorf("no more steps")
+	}
+	gdi.updateUIShowLine(erow)
+	return nil
+}
+
+func (gdi *GoDebugInstance) step(out bool) bool {
+	di := gdi.data.dataIndex
+	si := di.SelectedArrivalIndex
+	if si < 0 || si >= len(di.Msgs) {
+		return false
+	}
+	msg := di.Msgs[si]
+	depth := msg.Frame.depth()
+	for i := si + 1; i < len(di.Msgs); i++ {
+		m := di.Msgs[i]
+		if m.DLine.GoId != msg.DLine.GoId {
+			continue
+		}
+		d := m.Frame.depth()
+		if d < depth || (!out && d == depth) {
+			di.SelectedArrivalIndex = i
+			gdi.openArrivalIndexERow()
+			return true
+		}
+	}
+	return false
+}
+
+//----------
+
+func (gdi *GoDebugInstance) OpenStackERow() {
+	erow, isNew := gdi.ed.ExistingOrNewERow(goDebugStackRowName)
+	if isNew {
+		erow.ToolbarSetStrAfterNameClearHistory(" | Close")
+	}
+	erow.Flash()
+	gdi.ed.UI.RunOnUIGoRoutine(func() {
+		if !gdi.dataRLock() {
+			gdi.updateStackERow(nil)
+			return
+		}
+		defer gdi.dataRUnlock()
+		gdi.updateStackERow(gdi.data.dataIndex)
+	})
+}
+
+// Needs data read lock if dataIndex is not nil.
+func (gdi *GoDebugInstance) updateStackERow(di *GDDataIndex) {
+	info, ok := gdi.ed.ERowInfo(goDebugStackRowName)
+	if !ok || len(info.ERows) == 0 {
+		return
+	}
+
+	buf := &bytes.Buffer{}
+	if di == nil {
+		fmt.Fprintf(buf, "no godebug session\n")
+	} else {
+		di.writeStack(buf)
+	}
+
+	for _, erow := range info.ERows {
+		ta := erow.Row.TextArea
+		if err := ta.SetBytesClearHistory(buf.Bytes()); err != nil {
+			gdi.ed.Error(err)
+		}
+	}
+}
+
+//----------
+
+func (di *GDDataIndex) writeStack(buf *bytes.Buffer) {
+	si := di.SelectedArrivalIndex
+	if si < 0 || si >= len(di.Msgs) {
+		fmt.Fprintf(buf, "no step selected\n")
+		return
+	}
+	msg := di.Msgs[si]
+	fmt.Fprintf(buf, "step %d, goroutine %d\n", si, msg.DLine.GoId)
+
+	// location inside each frame
+	m := msg
+	for f := msg.Frame; ; f = f.Parent {
+		name := fmt.Sprintf("(goroutine %d)", msg.DLine.GoId)
+		if f != nil {
+			name = f.Name
+			if ice, ok := f.Enter.DLine.Item.(*debug.ItemCallEnter); ok {
+				name += "(" + godebug.StringifyItem(ice.Args) + ")"
+			}
+		}
+		fmt.Fprintf(buf, "%v: %v\n", di.msgLocation(m), name)
+		if f == nil {
+			break
+		}
+		m = f.Enter
+	}
+}
+
+// Filename with line/column if available.
+func (di *GDDataIndex) msgLocation(msg *GDLineMsg) string {
+	fi := msg.DLine.FileIndex
+	filename := di.Afds[fi].Filename
+	if src, ok := di.fileSrc(fi); ok {
+		rd := iorw.NewBytesReadWriter(src)
+		l, c, err := parseutil.IndexLineColumn(rd, msg.DLine.Offset)
+		if err == nil {
+			return fmt.Sprintf("%v:%v:%v", filename, l, c)
+		}
+	}
+	return filename
+}
@@ -0,0 +1,64 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/jmigpin/editor/core/godebug/debug"
+)
+
+func TestGDStack1(t *testing.T) {
+	di := NewGDDataIndex(nil)
+	add := func(goId int, item debug.Item) *GDLineMsg {
+		lm := &GDLineMsg{DLine: &debug.LineMsg{GoId: goId, Item: item}}
+		di.updateStack(lm)
+		return lm
+	}
+
+	// a := f1(1) + f2(2)
+	m1 := add(1, debug.ICe("f1", debug.IV(1)))
+	m2 := add(1, debug.IV(10)) // inside f1
+	m3 := add(2, debug.IV(0))  // other goroutine
+	m4 := add(1, debug.ICe("f2", debug.IV(2)))
+	m5 := add(1, debug.IV(20)) // inside f2
+	m6 := add(1, debug.IA(
+		debug.IL(debug.IV(30)),
+		debug.IL(debug.IB(debug.IV(30), 0,
+			debug.IC("f1", debug.IV(10), debug.IV(1)),
+			debug.IC("f2", debug.IV(20), debug.IV(2)),
+		)),
+	))
+
+	depths := []int{0, 1, 0, 1, 2, 0}
+	for i, m := range []*GDLineMsg{m1, m2, m3, m4, m5, m6} {
+		if d := m.Frame.depth(); d != depths[i] {
+			t.Fatalf("msg %v: expected depth %v, got %v", i+1, depths[i], d)
+		}
+	}
+	if m5.Frame.Name != "f2" || m5.Frame.Parent.Name != "f1" {
+		t.Fatalf("bad frames")
+	}
+	if di.stacks[1] != nil {
+		t.Fatalf("stack not empty")
+	}
+}
+
+func TestGDStack2(t *testing.T) {
+	di := NewGDDataIndex(nil)
+	add := func(item debug.Item) *GDLineMsg {
+		lm := &GDLineMsg{DLine: &debug.LineMsg{Item: item}}
+		di.updateStack(lm)
+		return lm
+	}
+
+	// recursive: g(g(1))
+	add(debug.ICe("g", debug.IV(1)))
+	m2 := add(debug.IV(1)) // inside inner g
+	ic := debug.IC("g", debug.IV(2), debug.IV(1))
+	add(debug.ICe("g", ic))
+	m4 := add(debug.IV(2)) // inside outer g
+	m5 := add(debug.IC("g", debug.IV(3), ic))
+
+	if m2.Frame.depth() != 1 || m4.Frame.depth() != 1 || m5.Frame.depth() != 0 {
+		t.Fatalf("bad depths: %v %v %v", m2.Frame.depth(), m4.Frame.depth(), m5.Frame.depth())
+	}
+}
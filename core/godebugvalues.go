@@ -0,0 +1,133 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jmigpin/editor/core/godebug/debug"
+)
+
+const GoDebugValuesRowName = "+GoDebugValues"
+
+//----------
+
+// Expandable view of the structured values of an annotation.
+type GDValuesView struct {
+	header   string
+	trees    []*debug.ValueTree
+	expanded map[string]bool // tree path -> expanded
+	lines    []string        // line -> tree path (set at render)
+}
+
+func NewGDValuesView(header string, trees []*debug.ValueTree) *GDValuesView {
+	vv := &GDValuesView{header: header, trees: trees}
+	vv.expanded = map[string]bool{}
+	for i := range trees {
+		vv.expanded[strconv.Itoa(i)] = true // roots start expanded
+	}
+	return vv
+}
+
+func (vv *GDValuesView) render() []byte {
+	buf := &bytes.Buffer{}
+	vv.lines = nil
+	addLine := func(path, s string) {
+		buf.WriteString(s + "\n")
+		vv.lines = append(vv.lines, path)
+	}
+
+	for _, l := range strings.Split(vv.header, "\n") {
+		addLine("", l)
+	}
+
+	var visit func(vt *debug.ValueTree, path string, depth int)
+	visit = func(vt *debug.ValueTree, path string, depth int) {
+		mark := " "
+		expanded := vv.expanded[path]
+		if len(vt.Childs) > 0 {
+			mark = "+"
+			if expanded {
+				mark = "-"
+			}
+		}
+		indent := strings.Repeat("    ", depth)
+		if vt.Name == "" { // root value
+			addLine(path, fmt.Sprintf("%s%s %s", indent, mark, vt.Str))
+		} else {
+			addLine(path, fmt.Sprintf("%s%s %s: %s", indent, mark, vt.Name, vt.Str))
+		}
+		if !expanded {
+			return
+		}
+		for i, c := range vt.Childs {
+			visit(c, path+"/"+strconv.Itoa(i), depth+1)
+		}
+		if vt.More {
+			indent2 := strings.Repeat("    ", depth+1)
+			addLine("", indent2+"  ...")
+		}
+	}
+	for i, vt := range vv.trees {
+		visit(vt, strconv.Itoa(i), 0)
+	}
+	return buf.Bytes()
+}
+
+func (vv *GDValuesView) toggleLine(line int) bool {
+	if line < 0 || line >= len(vv.lines) {
+		return false
+	}
+	path := vv.lines[line]
+	if path == "" {
+		return false
+	}
+	vv.expanded[path] = !vv.expanded[path]
+	return true
+}
+
+//----------
+
+func (gdi *GoDebugInstance) showValuesView(vv *GDValuesView) {
+	gdi.valuesView = vv
+	erow, isNew := gdi.ed.ExistingOrNewERow(GoDebugValuesRowName)
+	if isNew {
+		erow.ToolbarSetStrAfterNameClearHistory(" | Close")
+	}
+	erow.Row.TextArea.SetBytesClearPos(vv.render())
+	erow.Flash()
+}
+
+// Toggles the expansion of the value at the index. Returns false if nothing was toggled.
+func (gdi *GoDebugInstance) ToggleValueAtIndex(erow *ERow, index int) bool {
+	if erow.Info.Name() != GoDebugValuesRowName {
+		return false
+	}
+	vv := gdi.valuesView
+	if vv == nil {
+		return false
+	}
+
+	ta := erow.Row.TextArea
+	b, err := ta.Bytes()
+	if err != nil || index > len(b) {
+		return false
+	}
+	line := bytes.Count(b[:index], []byte("\n"))
+	if !vv.toggleLine(line) {
+		return false
+	}
+
+	// keep view position
+	ro := ta.RuneOffset()
+	ci := ta.TextCursor.Index()
+	if err := ta.SetBytesClearHistory(vv.render()); err != nil {
+		return false
+	}
+	ta.SetRuneOffset(ro)
+	if ci <= ta.Len() {
+		ta.TextCursor.SetIndex(ci)
+	}
+	return true
+}
@@ -0,0 +1,35 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/jmigpin/editor/core/godebug/debug"
+)
+
+func TestGDValuesView1(t *testing.T) {
+	vt := &debug.ValueTree{Str: "T{...}", Childs: []*debug.ValueTree{
+		{Name: "A", Str: "1"},
+		{Name: "B", Str: "[]int{...}", Childs: []*debug.ValueTree{{Name: "0", Str: "2"}}},
+	}}
+	vv := NewGDValuesView("header", []*debug.ValueTree{vt})
+
+	s := string(vv.render())
+	s2 := "header\n- T{...}\n      A: 1\n    + B: []int{...}\n"
+	if s != s2 {
+		t.Fatalf("%q", s)
+	}
+
+	// no value in the header
+	if vv.toggleLine(0) {
+		t.Fatal("header toggled")
+	}
+
+	if !vv.toggleLine(3) {
+		t.Fatal("expecting toggle")
+	}
+	s = string(vv.render())
+	s2 = "header\n- T{...}\n      A: 1\n    - B: []int{...}\n          0: 2\n"
+	if s != s2 {
+		t.Fatalf("%q", s)
+	}
+}
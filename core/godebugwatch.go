@@ -0,0 +1,218 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"sort"
+
+	"github.com/jmigpin/editor/core/godebug"
+)
+
+const goDebugWatchRowName = "+GoDebugWatch"
+
+//----------
+
+func (gdi *GoDebugInstance) AddWatches(exprs []string) error {
+	if len(exprs) == 0 {
+		return fmt.Errorf("missing expression")
+	}
+	for _, e := range exprs {
+		e2 := godebug.NormalizeExprStr(e)
+		if e2 == "" || gdi.watchIndex(e2) >= 0 {
+			continue
+		}
+		gdi.watches = append(gdi.watches, e2)
+	}
+
+	// ensure row
+	erow, isNew := gdi.ed.ExistingOrNewERow(goDebugWatchRowName)
+	if isNew {
+		erow.ToolbarSetStrAfterNameClearHistory(" | Close")
+	}
+	erow.Flash()
+
+	gdi.updateWatchERowOnUI()
+	return nil
+}
+
+// Removes all watches if no expressions are given.
+func (gdi *GoDebugInstance) RemoveWatches(exprs []string) error {
+	if len(exprs) == 0 {
+		gdi.watches = nil
+	}
+	for _, e := range exprs {
+		i := gdi.watchIndex(godebug.NormalizeExprStr(e))
+		if i < 0 {
+			return fmt.Errorf("watch not found: %v", e)
+		}
+		gdi.watches = append(gdi.watches[:i], gdi.watches[i+1:]...)
+	}
+	gdi.updateWatchERowOnUI()
+	return nil
+}
+
+func (gdi *GoDebugInstance) watchIndex(expr string) int {
+	for i, e := range gdi.watches {
+		if e == expr {
+			return i
+		}
+	}
+	return -1
+}
+
+//----------
+
+func (gdi *GoDebugInstance) updateWatchERowOnUI() {
+	gdi.ed.UI.RunOnUIGoRoutine(func() {
+		if !gdi.dataRLock() {
+			gdi.updateWatchERow(nil)
+			return
+		}
+		defer gdi.dataRUnlock()
+		gdi.updateWatchERow(gdi.data.dataIndex)
+	})
+}
+
+// Needs data read lock if dataIndex is not nil. The values are computed in a goroutine since it might need to read the files source.
+func (gdi *GoDebugInstance) updateWatchERow(di *GDDataIndex) {
+	info, ok := gdi.ed.ERowInfo(goDebugWatchRowName)
+	if !ok || len(info.ERows) == 0 {
+		return
+	}
+
+	gdi.watchesN++
+	n := gdi.watchesN
+	watches := append([]string(nil), gdi.watches...)
+
+	if di == nil {
+		gdi.setWatchERowBytes(watchesBytes(nil, watches))
+		return
+	}
+	go func() {
+		if !gdi.dataRLock() {
+			return
+		}
+		var b []byte
+		if gdi.data.dataIndex == di {
+			b = watchesBytes(di, watches)
+		}
+		gdi.dataRUnlock()
+		if b == nil {
+			return
+		}
+		gdi.ed.UI.RunOnUIGoRoutine(func() {
+			if n == gdi.watchesN {
+				gdi.setWatchERowBytes(b)
+			}
+		})
+	}()
+}
+
+func (gdi *GoDebugInstance) setWatchERowBytes(b []byte) {
+	info, ok := gdi.ed.ERowInfo(goDebugWatchRowName)
+	if !ok {
+		return
+	}
+	for _, erow := range info.ERows {
+		ta := erow.Row.TextArea
+		ci, ro := ta.TextCursor.Index(), ta.RuneOffset()
+		if err := ta.SetBytesClearHistory(b); err != nil {
+			gdi.ed.Error(err)
+			continue
+		}
+		if ci <= ta.Len() {
+			ta.TextCursor.SetIndex(ci)
+			ta.SetRuneOffset(ro)
+		}
+	}
+}
+
+// Needs data read lock if dataIndex is not nil.
+func watchesBytes(di *GDDataIndex, watches []string) []byte {
+	buf := &bytes.Buffer{}
+	if di == nil {
+		fmt.Fprintf(buf, "no godebug session\n")
+	} else {
+		fmt.Fprintf(buf, "step %d/%d\n", di.SelectedArrivalIndex, di.GlobalArrivalIndex)
+	}
+	for _, e := range watches {
+		v := "?"
+		if di != nil {
+			if u, ok := di.exprValue(e); ok {
+				v = u
+			}
+		}
+		fmt.Fprintf(buf, "%v = %v\n", e, v)
+	}
+	return buf.Bytes()
+}
+
+//----------
+
+// Value of the expression in the latest msg at or before the selected arrival index.
+func (di *GDDataIndex) exprValue(expr string) (string, bool) {
+	di.cache.Lock()
+	defer di.cache.Unlock()
+	best, bestIndex := "", -1
+	for fi, file := range di.Files {
+		ie := di.fileItemExprs(fi)
+		if ie == nil {
+			continue
+		}
+		for _, lm := range file.Lines {
+			k := sort.Search(len(lm.Msgs), func(i int) bool {
+				return lm.Msgs[i].GlobalArrivalIndex > di.SelectedArrivalIndex
+			})
+			k--
+			if k < 0 {
+				continue
+			}
+			msg := lm.Msgs[k]
+			if msg.GlobalArrivalIndex <= bestIndex {
+				continue
+			}
+			if v, ok := msg.exprValues(ie)[expr]; ok {
+				best, bestIndex = v, msg.GlobalArrivalIndex
+			}
+		}
+	}
+	return best, bestIndex >= 0
+}
+
+// Needs the cache lock. Returns nil if the file source is not available.
+func (di *GDDataIndex) fileItemExprs(findex int) *godebug.ItemExprs {
+	if ie, ok := di.cache.itemExprs[findex]; ok {
+		return ie
+	}
+	var ie *godebug.ItemExprs
+	if src, ok := di.fileSrc(findex); ok {
+		ie, _ = godebug.NewItemExprs(di.Afds[findex].Filename, src)
+	}
+	di.cache.itemExprs[findex] = ie
+	return ie
+}
+
+// Needs the cache lock. Returns false if the file source is not available (ex: edited since the annotation).
+func (di *GDDataIndex) fileSrc(findex int) ([]byte, bool) {
+	if src, ok := di.cache.fileSrcs[findex]; ok {
+		return src, src != nil
+	}
+	afd := di.Afds[findex]
+	src, err := ioutil.ReadFile(afd.Filename)
+	if err != nil || !bytes.Equal(bytesHash(src), afd.FileHash) {
+		src = nil
+	}
+	di.cache.fileSrcs[findex] = src
+	return src, src != nil
+}
+
+//----------
+
+// Needs the data index cache lock.
+func (msg *GDLineMsg) exprValues(ie *godebug.ItemExprs) map[string]string {
+	if msg.cachedExprValues == nil {
+		msg.cachedExprValues = ie.Values(msg.DLine)
+	}
+	return msg.cachedExprValues
+}
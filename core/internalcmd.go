@@ -181,6 +181,12 @@ func internalCmd(ed *Editor, part *toolbarparser.Part, erow *ERow) {
 		return
 	}
 
+	// acme style cmds on the row selection
+	if typ, cargs := pipeCmdPartArgs(part); typ != PipeCmdNone {
+		rowCmdErr(func(e *ERow) error { return PipeCmd(e, typ, cargs) })
+		return
+	}
+
 	// have a plugin handle the cmd
 	e := currentERow() // could be nil
 	handled := ed.Plugins.RunToolbarCmd(e, part)
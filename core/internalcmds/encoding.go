@@ -0,0 +1,40 @@
+package internalcmds
+
+import (
+	"fmt"
+
+	"github.com/jmigpin/editor/core"
+	"github.com/jmigpin/editor/util/encodingutil"
+)
+
+// Usage: SetEncoding <utf8|utf8bom|utf16le|utf16be|latin1>
+func SetEncoding(args *core.InternalCmdArgs) error {
+	a := args.Part.ArgsUnquoted()
+	if len(a) != 2 {
+		return fmt.Errorf("expecting encoding name")
+	}
+	enc, err := encodingutil.ParseEncoding(a[1])
+	if err != nil {
+		return err
+	}
+	info := args.ERow.Info
+	f := info.Format()
+	f.Encoding = enc
+	return info.SetFormat(f)
+}
+
+// Usage: SetLineEnding <lf|crlf>
+func SetLineEnding(args *core.InternalCmdArgs) error {
+	a := args.Part.ArgsUnquoted()
+	if len(a) != 2 {
+		return fmt.Errorf("expecting line ending name")
+	}
+	le, err := encodingutil.ParseLineEnding(a[1])
+	if err != nil {
+		return err
+	}
+	info := args.ERow.Info
+	f := info.Format()
+	f.LineEnding = le
+	return info.SetFormat(f)
+}
@@ -10,6 +10,7 @@ func init() {
 	ic.Set(&core.InternalCmd{"Exit", true, Exit})
 
 	ic.Set(&core.InternalCmd{"SaveSession", true, SaveSession})
+	ic.Set(&core.InternalCmd{"SaveProjectSession", true, SaveProjectSession})
 	ic.Set(&core.InternalCmd{"OpenSession", true, OpenSession})
 	ic.Set(&core.InternalCmd{"DeleteSession", true, DeleteSession})
 	ic.Set(&core.InternalCmd{"ListSessions", true, ListSessions})
@@ -26,12 +27,29 @@ func init() {
 	ic.Set(&core.InternalCmd{"SaveAllFiles", true, SaveAllFiles})
 
 	ic.Set(&core.InternalCmd{"Reload", false, Reload})
+	ic.Set(&core.InternalCmd{"Merge", false, Merge})
 	ic.Set(&core.InternalCmd{"ReloadAllFiles", true, ReloadAllFiles})
 	ic.Set(&core.InternalCmd{"ReloadAll", true, ReloadAll})
 
+	ic.Set(&core.InternalCmd{"SetEncoding", false, SetEncoding})
+	ic.Set(&core.InternalCmd{"SetLineEnding", false, SetLineEnding})
+
 	ic.Set(&core.InternalCmd{"Stop", false, Stop})
+	ic.Set(&core.InternalCmd{"SendEOF", false, SendEOF})
+	ic.Set(&core.InternalCmd{"Rerun", false, Rerun})
+	ic.Set(&core.InternalCmd{"Watch", false, Watch})
 	ic.Set(&core.InternalCmd{"Clear", false, Clear})
 
+	ic.Set(&core.InternalCmd{"Jobs", true, Jobs})
+	ic.Set(&core.InternalCmd{"JobShow", true, JobShow})
+	ic.Set(&core.InternalCmd{"JobStop", true, JobStop})
+	ic.Set(&core.InternalCmd{"JobKill", true, JobKill})
+
+	ic.Set(&core.InternalCmd{"Recovery", true, Recovery})
+	ic.Set(&core.InternalCmd{"RecoverDiff", true, RecoverDiff})
+	ic.Set(&core.InternalCmd{"RecoverRestore", true, RecoverRestore})
+	ic.Set(&core.InternalCmd{"RecoverDiscard", true, RecoverDiscard})
+
 	ic.Set(&core.InternalCmd{"Find", false, Find})
 	ic.Set(&core.InternalCmd{"Replace", false, Replace})
 	ic.Set(&core.InternalCmd{"GotoLine", false, GotoLine})
@@ -40,9 +58,13 @@ func init() {
 	ic.Set(&core.InternalCmd{"RuneCodes", false, RuneCodes})
 	ic.Set(&core.InternalCmd{"FontRunes", false, FontRunes})
 
+	ic.Set(&core.InternalCmd{"AllowEnv", false, AllowEnv})
+
 	ic.Set(&core.InternalCmd{"XdgOpenDir", false, XdgOpenDir})
 
 	ic.Set(&core.InternalCmd{"ListDir", false, ListDir})
+	ic.Set(&core.InternalCmd{"ToggleDir", false, ToggleDir})
+	ic.Set(&core.InternalCmd{"Apply", false, Apply})
 
 	ic.Set(&core.InternalCmd{"GoRename", false, GoRename})
 	ic.Set(&core.InternalCmd{"GoDebug", false, GoDebug})
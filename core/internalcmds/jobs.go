@@ -0,0 +1,35 @@
+package internalcmds
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/jmigpin/editor/core"
+)
+
+func Jobs(args *core.InternalCmdArgs) error {
+	core.ListJobs(args.Ed)
+	return nil
+}
+
+func JobShow(args *core.InternalCmdArgs) error {
+	return idCmd(args, core.JobShow)
+}
+func JobStop(args *core.InternalCmdArgs) error {
+	return idCmd(args, core.JobStop)
+}
+func JobKill(args *core.InternalCmdArgs) error {
+	return idCmd(args, core.JobKill)
+}
+
+func idCmd(args0 *core.InternalCmdArgs, fn func(*core.Editor, int) error) error {
+	args := args0.Part.Args[1:]
+	if len(args) != 1 {
+		return fmt.Errorf("expecting 1 argument")
+	}
+	id, err := strconv.Atoi(args[0].Str())
+	if err != nil {
+		return err
+	}
+	return fn(args0.Ed, id)
+}
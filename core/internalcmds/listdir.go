@@ -31,3 +31,38 @@ func ListDir(args0 *core.InternalCmdArgs) error {
 
 	return nil
 }
+
+//----------
+
+func ToggleDir(args0 *core.InternalCmdArgs) error {
+	erow := args0.ERow
+	part := args0.Part
+
+	if !erow.Info.IsDir() {
+		return fmt.Errorf("not a directory")
+	}
+
+	args := part.Args[1:]
+	if len(args) > 1 {
+		return fmt.Errorf("usage: ToggleDir [<subdir>]")
+	}
+	name := ""
+	if len(args) == 1 {
+		name = args[0].UnquotedStr()
+	} else {
+		u, err := core.ListDirCursorName(erow)
+		if err != nil {
+			return err
+		}
+		name = u
+	}
+	return core.ToggleDirERow(erow, name)
+}
+
+func Apply(args0 *core.InternalCmdArgs) error {
+	erow := args0.ERow
+	if !erow.Info.IsDir() {
+		return fmt.Errorf("not a directory")
+	}
+	return core.ApplyListDirERow(erow)
+}
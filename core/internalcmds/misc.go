@@ -2,6 +2,7 @@ package internalcmds
 
 import (
 	"fmt"
+	"path/filepath"
 
 	"github.com/jmigpin/editor/core"
 	"github.com/jmigpin/editor/ui"
@@ -23,6 +24,10 @@ func SaveSession(args *core.InternalCmdArgs) error {
 	core.SaveSession(args.Ed, args.Part)
 	return nil
 }
+func SaveProjectSession(args *core.InternalCmdArgs) error {
+	core.SaveProjectSession(args.Ed, args.Part)
+	return nil
+}
 func OpenSession(args *core.InternalCmdArgs) error {
 	core.OpenSession(args.Ed, args.Part)
 	return nil
@@ -83,6 +88,16 @@ func Reload(args *core.InternalCmdArgs) error {
 	args.ERow.Reload()
 	return nil
 }
+func Merge(args *core.InternalCmdArgs) error {
+	n, err := args.ERow.Info.Merge()
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return fmt.Errorf("merge: %v conflicts", n)
+	}
+	return nil
+}
 func ReloadAllFiles(args *core.InternalCmdArgs) error {
 	var me iout.MultiError
 	for _, info := range args.Ed.ERowInfos() {
@@ -112,6 +127,31 @@ func Stop(args *core.InternalCmdArgs) error {
 	return nil
 }
 
+func SendEOF(args *core.InternalCmdArgs) error {
+	return args.ERow.Exec.SendEOF()
+}
+
+func Rerun(args *core.InternalCmdArgs) error {
+	return args.ERow.Exec.Rerun()
+}
+
+//----------
+
+func Watch(args *core.InternalCmdArgs) error {
+	erow := args.ERow
+	part := args.Part
+
+	// stop watching
+	if len(part.Args) == 1 {
+		erow.Watch.Stop()
+		return nil
+	}
+
+	cargs := core.ShellCmdArgs(part.Args[1:])
+	core.ExternalCmdWatch(erow, cargs)
+	return nil
+}
+
 //----------
 
 func Clear(args *core.InternalCmdArgs) error {
@@ -121,6 +161,25 @@ func Clear(args *core.InternalCmdArgs) error {
 
 //----------
 
+// Allows the row ".editorenv" file (or the one in the row directory) to be used.
+func AllowEnv(args *core.InternalCmdArgs) error {
+	erow := args.ERow
+	if erow.Info.IsSpecial() {
+		return fmt.Errorf("can't run on special row")
+	}
+	filename := erow.Info.Name()
+	if filepath.Base(filename) != core.EnvProfilesFilename {
+		filename = filepath.Join(erow.Info.Dir(), core.EnvProfilesFilename)
+	}
+	if err := args.Ed.EnvProfiles.AllowFile(filename); err != nil {
+		return err
+	}
+	args.Ed.Messagef("allowed: %v", filename)
+	return nil
+}
+
+//----------
+
 func XdgOpenDir(args *core.InternalCmdArgs) error {
 	erow := args.ERow
 
@@ -146,6 +205,26 @@ func XdgOpenDir(args *core.InternalCmdArgs) error {
 
 func GoDebug(args *core.InternalCmdArgs) error {
 	args2 := args.Part.ArgsUnquoted()
+
+	// editor side commands
+	if len(args2) >= 2 {
+		switch args2[1] {
+		case "watch":
+			return args.Ed.GoDebug.AddWatches(args2[2:])
+		case "unwatch":
+			return args.Ed.GoDebug.RemoveWatches(args2[2:])
+		case "stack":
+			args.Ed.GoDebug.OpenStackERow()
+			return nil
+		case "stepover":
+			return args.Ed.GoDebug.Step(args.ERow, false)
+		case "stepout":
+			return args.Ed.GoDebug.Step(args.ERow, true)
+		case "testhere":
+			return args.Ed.GoDebug.StartTestHere(args.ERow, args2[2:])
+		}
+	}
+
 	return args.Ed.GoDebug.Start(args.ERow, args2)
 }
 
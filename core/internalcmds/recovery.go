@@ -0,0 +1,20 @@
+package internalcmds
+
+import (
+	"github.com/jmigpin/editor/core"
+)
+
+func Recovery(args *core.InternalCmdArgs) error {
+	core.ListRecovery(args.Ed)
+	return nil
+}
+
+func RecoverDiff(args *core.InternalCmdArgs) error {
+	return idCmd(args, core.RecoverDiff)
+}
+func RecoverRestore(args *core.InternalCmdArgs) error {
+	return idCmd(args, core.RecoverRestore)
+}
+func RecoverDiscard(args *core.InternalCmdArgs) error {
+	return idCmd(args, core.RecoverDiscard)
+}
@@ -0,0 +1,139 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/jmigpin/editor/util/osutil"
+)
+
+// Time given to a job to exit after a termination signal before being killed.
+const jobStopTimeout = 3 * time.Second
+
+// Row with the jobs list.
+const JobsRowName = "+Jobs"
+
+// Execution running in a row (external cmd, godebug session).
+type Job struct {
+	Id    int
+	ERow  *ERow
+	Start time.Time
+	Dir   string
+	Cmd   string
+
+	cmd *exec.Cmd // can be nil
+}
+
+func (job *Job) pid() int {
+	if job.cmd == nil || job.cmd.Process == nil {
+		return 0
+	}
+	return job.cmd.Process.Pid
+}
+
+//----------
+
+var jobIds int32
+
+func newJobId() int {
+	return int(atomic.AddInt32(&jobIds, 1))
+}
+
+//----------
+
+// Jobs of all rows sorted by id. Used from the ui goroutine.
+func Jobs(ed *Editor) []Job {
+	u := []Job{}
+	for _, info := range ed.ERowInfos() {
+		for _, erow := range info.ERows {
+			if job, ok := erow.Exec.Job(); ok {
+				u = append(u, job)
+			}
+		}
+	}
+	sort.Slice(u, func(a, b int) bool {
+		return u[a].Id < u[b].Id
+	})
+	return u
+}
+
+func findJob(ed *Editor, id int) (Job, error) {
+	for _, job := range Jobs(ed) {
+		if job.Id == id {
+			return job, nil
+		}
+	}
+	return Job{}, fmt.Errorf("job not found: %v", id)
+}
+
+//----------
+
+func ListJobs(ed *Editor) {
+	jobs := Jobs(ed)
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "jobs: %d\n", len(jobs))
+	for _, job := range jobs {
+		fmt.Fprintf(buf, "JobShow %[1]v JobStop %[1]v JobKill %[1]v\n", job.Id)
+		pid := "-"
+		if p := job.pid(); p != 0 {
+			pid = fmt.Sprintf("%v", p)
+		}
+		start := job.Start.Format("15:04:05")
+		dur := time.Since(job.Start).Round(time.Second)
+		fmt.Fprintf(buf, "\tpid %v, %v (%v), %v: %v\n", pid, start, dur, ed.HomeVars.Encode(job.Dir), job.Cmd)
+	}
+
+	erow, _ := ed.ExistingOrNewERow(JobsRowName)
+	erow.Row.TextArea.SetBytesClearPos(buf.Bytes())
+	erow.Flash()
+}
+
+//----------
+
+// Makes the row of the job visible.
+func JobShow(ed *Editor, id int) error {
+	job, err := findJob(ed, id)
+	if err != nil {
+		return err
+	}
+	erow := job.ERow
+	erow.MakeIndexVisibleAndFlash(erow.Row.TextArea.Len())
+	return nil
+}
+
+// Sends a termination signal, and kills the job if it is still running after a timeout.
+func JobStop(ed *Editor, id int) error {
+	job, err := findJob(ed, id)
+	if err != nil {
+		return err
+	}
+	if job.cmd == nil || job.cmd.Process == nil {
+		job.ERow.Exec.Stop()
+		return nil
+	}
+	if err := osutil.TermExecCmd(job.cmd); err != nil {
+		return err
+	}
+	time.AfterFunc(jobStopTimeout, func() {
+		ed.UI.RunOnUIGoRoutine(func() {
+			if job2, ok := job.ERow.Exec.Job(); ok && job2.Id == id {
+				job.ERow.Exec.Stop() // kills
+			}
+		})
+	})
+	return nil
+}
+
+func JobKill(ed *Editor, id int) error {
+	job, err := findJob(ed, id)
+	if err != nil {
+		return err
+	}
+	job.ERow.Exec.Stop() // kills
+	return nil
+}
@@ -1,54 +1,214 @@
 package core
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
 
+	"github.com/jmigpin/editor/core/fsys"
 	"github.com/jmigpin/editor/core/parseutil"
+	"github.com/jmigpin/editor/util/diffutil"
 )
 
 func ListDirERow(erow *ERow, filepath string, tree, hidden bool) {
+	st := &listDirState{tree: tree, hidden: hidden, toggled: map[string]bool{}}
+	erow.listDir = st
+
 	// clear
 	erow.Row.TextArea.SetStrClearHistory("")
 	erow.Row.TextArea.ClearPos()
 
 	erow.Exec.Start(func(ctx context.Context, w io.Writer) error {
-		return ListDirContext(ctx, w, erow.Info.Name(), tree, hidden)
+		buf := &bytes.Buffer{}
+		w2 := io.MultiWriter(w, buf)
+		if err := st.write(ctx, w2, erow.Info.Name()); err != nil {
+			return err
+		}
+		erow.Ed.UI.RunOnUIGoRoutine(func() {
+			st.base = buf.Bytes()
+		})
+		return nil
 	})
+
+	// sub dirs listing is refreshed on changes
+	if tree {
+		erow.Watch.StartRefresh(hidden, func() {
+			// not refreshed if there are edits to apply
+			_ = refreshListDirERow(erow, false)
+		})
+	} else {
+		erow.Watch.StopRefresh()
+	}
+}
+
+// Lists the dir again, keeping the cursor and the scroll position. Fails if the listing has edits (unless forced).
+func refreshListDirERow(erow *ERow, force bool) error {
+	st := erow.listDir
+	if st == nil {
+		return fmt.Errorf("not a dir listing (run ListDir)")
+	}
+	if !force {
+		if err := st.checkNoEdits(erow); err != nil {
+			return err
+		}
+	}
+	go func() {
+		buf := &bytes.Buffer{}
+		ctx := context.Background()
+		if err := st.write(ctx, buf, erow.Info.Name()); err != nil {
+			erow.Ed.Error(err)
+			return
+		}
+		erow.Ed.UI.RunOnUIGoRoutine(func() {
+			if erow.listDir != st {
+				return // listed again meanwhile
+			}
+			ta := erow.Row.TextArea
+			old, err := ta.Bytes()
+			if err != nil {
+				erow.Ed.Error(err)
+				return
+			}
+			b := buf.Bytes()
+			if !force && !bytes.Equal(old, st.base) {
+				return // edited meanwhile
+			}
+			st.base = b
+			if bytes.Equal(old, b) {
+				return
+			}
+			m := diffutil.NewOffsetMapper(old, b)
+			ci := m.Map(ta.TextCursor.Index())
+			offset := m.Map(ta.RuneOffset())
+			if err := ta.SetBytesClearHistory(b); err != nil {
+				erow.Ed.Error(err)
+				return
+			}
+			ta.TextCursor.SetIndex(ci)
+			ta.SetRuneOffset(offset)
+		})
+	}()
+	return nil
+}
+
+//----------
+
+// Expands or collapses the sub dir (relative to the row dir) in place.
+func ToggleDirERow(erow *ERow, name string) error {
+	st := erow.listDir
+	if st == nil {
+		return fmt.Errorf("not a dir listing (run ListDir)")
+	}
+	if err := st.checkNoEdits(erow); err != nil {
+		return err
+	}
+	name = filepath.Clean(name)
+	if !listDirIsSubPath(name) {
+		return fmt.Errorf("not a sub dir: %q", name)
+	}
+	fi, err := fsys.Stat(fsys.Join(erow.Info.Name(), name))
+	if err != nil {
+		return err
+	}
+	if !fi.IsDir() {
+		return fmt.Errorf("not a directory: %v", name)
+	}
+	st.toggled[name] = !st.expanded(name)
+	return refreshListDirERow(erow, false)
+}
+
+// Sub dir name at the cursor line of the listing.
+func ListDirCursorName(erow *ERow) (string, error) {
+	ta := erow.Row.TextArea
+	b, err := ta.Bytes()
+	if err != nil {
+		return "", err
+	}
+	i := ta.TextCursor.Index()
+	if i > len(b) {
+		i = len(b)
+	}
+	s := bytes.LastIndexByte(b[:i], '\n') + 1
+	e := bytes.IndexByte(b[i:], '\n')
+	if e < 0 {
+		e = len(b)
+	} else {
+		e += i
+	}
+	return listDirLineName(string(b[s:e])), nil
 }
 
 //----------
 
+// Listing options of a dir row.
+type listDirState struct {
+	tree, hidden bool
+	toggled      map[string]bool // sub dirs expanded/collapsed in place
+	base         []byte          // listing as last written, the edits are compared against it
+}
+
+func (st *listDirState) expanded(name string) bool {
+	if v, ok := st.toggled[name]; ok {
+		return v
+	}
+	return st.tree
+}
+
+func (st *listDirState) write(ctx context.Context, w io.Writer, name string) error {
+	if err := writeListDirTop(w); err != nil {
+		return err
+	}
+	return listDirContext(ctx, w, name, "", st.expanded, st.hidden)
+}
+
+func (st *listDirState) checkNoEdits(erow *ERow) error {
+	b, err := erow.Row.TextArea.Bytes()
+	if err != nil {
+		return err
+	}
+	if st.base == nil {
+		return fmt.Errorf("listing is not done")
+	}
+	if !bytes.Equal(b, st.base) {
+		return errListDirEdits
+	}
+	return nil
+}
+
+var errListDirEdits = errors.New("listing has edits (use Apply, or ListDir to discard)")
+
+//----------
+
 func ListDirContext(ctx context.Context, w io.Writer, filepath string, tree, hidden bool) error {
-	// "../" at the top
-	u := ".." + string(os.PathSeparator)
-	if _, err := w.Write([]byte(u + "\n")); err != nil {
+	if err := writeListDirTop(w); err != nil {
 		return err
 	}
+	expanded := func(string) bool { return tree }
+	return listDirContext(ctx, w, filepath, "", expanded, hidden)
+}
 
-	return listDirContext(ctx, w, filepath, "", tree, hidden)
+// "../" at the top
+func writeListDirTop(w io.Writer) error {
+	u := ".." + string(os.PathSeparator)
+	_, err := w.Write([]byte(u + "\n"))
+	return err
 }
 
-func listDirContext(ctx context.Context, w io.Writer, fpath, addedFilepath string, tree, hidden bool) error {
-	fp2 := filepath.Join(fpath, addedFilepath)
+func listDirContext(ctx context.Context, w io.Writer, fpath, addedFilepath string, expanded func(string) bool, hidden bool) error {
+	fp2 := fsys.Join(fpath, addedFilepath)
 
 	out := func(s string) bool {
 		_, err := w.Write([]byte(s))
 		return err == nil
 	}
 
-	f, err := os.Open(fp2)
-	if err != nil {
-		out(err.Error())
-		return nil
-	}
-
-	fis, err := f.Readdir(-1)
-	f.Close() // close as soon as possible
+	fis, err := fsys.ReadDir(fp2)
 	if err != nil {
 		out(err.Error())
 		return nil
@@ -82,9 +242,12 @@ func listDirContext(ctx context.Context, w io.Writer, fpath, addedFilepath strin
 			return nil
 		}
 
-		if fi.IsDir() && tree {
+		if fi.IsDir() {
 			afp := filepath.Join(addedFilepath, name)
-			err := listDirContext(ctx, w, fpath, afp, tree, hidden)
+			if !expanded(afp) {
+				continue
+			}
+			err := listDirContext(ctx, w, fpath, afp, expanded, hidden)
 			if err != nil {
 				return err
 			}
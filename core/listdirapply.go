@@ -0,0 +1,314 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jmigpin/editor/core/fsys"
+	"github.com/jmigpin/editor/core/parseutil"
+	"github.com/jmigpin/editor/ui"
+	"github.com/jmigpin/editor/util/diffutil"
+	"github.com/jmigpin/editor/util/osutil"
+)
+
+// Applies the edits made to the dir listing text to the filesystem (create, rename, move, copy, delete to trash), and lists the dir again.
+func ApplyListDirERow(erow *ERow) error {
+	st := erow.listDir
+	if st == nil {
+		return fmt.Errorf("not a dir listing (run ListDir)")
+	}
+	if !fsys.IsLocal(erow.Info.Name()) {
+		return fmt.Errorf("not available for remote dirs")
+	}
+	if erow.Row.HasState(ui.RowStateExecuting) || st.base == nil {
+		return fmt.Errorf("listing is not done")
+	}
+	if erow.Exec.limitOn() {
+		return fmt.Errorf("not available with $maxlines/$maxbytes (the listing can be incomplete)")
+	}
+	b, err := erow.Row.TextArea.Bytes()
+	if err != nil {
+		return err
+	}
+
+	ops, err := listDirOps(listDirLines(st.base), listDirLines(b))
+	if err != nil {
+		return err
+	}
+	err = applyListDirOps(erow.Info.Name(), ops)
+
+	// list again even on error, some ops could have been applied
+	if err2 := refreshListDirERow(erow, true); err == nil {
+		err = err2
+	}
+	return err
+}
+
+//----------
+
+type listDirOpType int
+
+const (
+	ldoCreate listDirOpType = iota // dst (dir if it ends with a separator)
+	ldoCopy                        // src to dst
+	ldoMove                        // src to dst (rename)
+	ldoTrash                       // src
+)
+
+// Names are relative to the listed dir.
+type listDirOp struct {
+	typ      listDirOpType
+	src, dst string
+}
+
+// Lines of the listing in the original (old) and edited (cur) text. A line removed and a line added with the same base name is a move. Other changed lines are renames if the number of lines of the change is kept. An added line "<src> -> <dst>" is a copy. Other removed lines are deleted (to trash), and other added lines are created.
+func listDirOps(old, cur []string) ([]*listDirOp, error) {
+	ops := []*listDirOp{}
+	dels, ins := []string{}, []string{}
+
+	edits := diffutil.Diff(old, cur)
+	for i := 0; i < len(edits); {
+		if edits[i].Type == diffutil.EqualEdit {
+			i++
+			continue
+		}
+		// group of consecutive changes
+		d, a := []string{}, []string{}
+		for ; i < len(edits) && edits[i].Type != diffutil.EqualEdit; i++ {
+			e := edits[i]
+			switch e.Type {
+			case diffutil.DeleteEdit:
+				d = append(d, old[e.A:e.A+e.N]...)
+			case diffutil.InsertEdit:
+				a = append(a, cur[e.B:e.B+e.N]...)
+			}
+		}
+
+		// copies
+		a2 := []string{}
+		copySrcs := map[string]bool{}
+		for _, l := range a {
+			if k := strings.Index(l, " -> "); k >= 0 {
+				src, dst := listDirLineName(l[:k]), listDirLineName(l[k+4:])
+				ops = append(ops, &listDirOp{typ: ldoCopy, src: src, dst: dst})
+				copySrcs[filepath.Clean(src)] = true
+				continue
+			}
+			a2 = append(a2, l)
+		}
+		a = a2
+		// the source line edited in place into the copy line is kept
+		d2 := []string{}
+		for _, l := range d {
+			if !copySrcs[filepath.Clean(listDirLineName(l))] {
+				d2 = append(d2, l)
+			}
+		}
+		d = d2
+
+		// moves inside the group, then renames in place
+		d, a = listDirMoves(d, a, &ops)
+		if len(d) == len(a) {
+			for k := range d {
+				src, dst := listDirLineName(d[k]), listDirLineName(a[k])
+				if filepath.Clean(src) != filepath.Clean(dst) {
+					ops = append(ops, &listDirOp{typ: ldoMove, src: src, dst: dst})
+				}
+			}
+			continue
+		}
+		dels = append(dels, d...)
+		ins = append(ins, a...)
+	}
+
+	// moves between groups
+	dels, ins = listDirMoves(dels, ins, &ops)
+
+	for _, l := range ins {
+		ops = append(ops, &listDirOp{typ: ldoCreate, dst: listDirLineName(l)})
+	}
+	for _, l := range dels {
+		ops = append(ops, &listDirOp{typ: ldoTrash, src: listDirLineName(l)})
+	}
+
+	for _, op := range ops {
+		for _, name := range []string{op.src, op.dst} {
+			if name != "" && !listDirIsSubPath(name) {
+				return nil, fmt.Errorf("not inside the listed dir: %q", name)
+			}
+		}
+	}
+	return ops, nil
+}
+
+// Removed and added lines with the same base name are moves (the same name is a line that was reordered). Returns the lines left.
+func listDirMoves(dels, ins []string, ops *[]*listDirOp) ([]string, []string) {
+	dels2 := []string{}
+	ins = append([]string{}, ins...)
+	for _, l := range dels {
+		src := listDirLineName(l)
+		found := false
+		for k, l2 := range ins {
+			dst := listDirLineName(l2)
+			if filepath.Base(src) == filepath.Base(dst) {
+				if filepath.Clean(src) != filepath.Clean(dst) {
+					*ops = append(*ops, &listDirOp{typ: ldoMove, src: src, dst: dst})
+				}
+				ins = append(ins[:k], ins[k+1:]...)
+				found = true
+				break
+			}
+		}
+		if !found {
+			dels2 = append(dels2, l)
+		}
+	}
+	return dels2, ins
+}
+
+// Order: create dirs, copy, move (deeper first), create files, trash (skipping the ones inside trashed dirs). The sources and destinations are checked before any change is made.
+func applyListDirOps(dir string, ops []*listDirOp) error {
+	join := func(name string) string {
+		return filepath.Join(dir, name)
+	}
+	exists := func(name string) bool {
+		_, err := os.Lstat(join(name))
+		return err == nil
+	}
+
+	// check
+	dsts := map[string]bool{}
+	for _, op := range ops {
+		if op.src != "" && !exists(op.src) {
+			return fmt.Errorf("not found: %v", op.src)
+		}
+		if op.dst != "" {
+			d := filepath.Clean(op.dst)
+			if exists(d) || dsts[d] {
+				return fmt.Errorf("already exists: %v", op.dst)
+			}
+			dsts[d] = true
+		}
+		if op.src != "" && op.dst != "" && osutil.FilepathHasDirPrefix(join(op.dst), join(op.src)) {
+			return fmt.Errorf("destination inside the source: %v -> %v", op.src, op.dst)
+		}
+	}
+
+	byType := func(typ listDirOpType) []*listDirOp {
+		u := []*listDirOp{}
+		for _, op := range ops {
+			if op.typ == typ {
+				u = append(u, op)
+			}
+		}
+		return u
+	}
+	isDir := func(name string) bool {
+		return strings.HasSuffix(name, string(os.PathSeparator))
+	}
+	mkdirParent := func(name string) error {
+		return os.MkdirAll(filepath.Dir(join(name)), 0755)
+	}
+
+	creates := byType(ldoCreate)
+	for _, op := range creates {
+		if isDir(op.dst) {
+			if err := os.MkdirAll(join(op.dst), 0755); err != nil {
+				return err
+			}
+		}
+	}
+	for _, op := range byType(ldoCopy) {
+		if err := mkdirParent(op.dst); err != nil {
+			return err
+		}
+		if err := osutil.CopyPath(join(op.src), join(op.dst)); err != nil {
+			return err
+		}
+	}
+	moves := byType(ldoMove)
+	sort.SliceStable(moves, func(i, j int) bool {
+		return listDirDepth(moves[i].src) > listDirDepth(moves[j].src)
+	})
+	for _, op := range moves {
+		if err := mkdirParent(op.dst); err != nil {
+			return err
+		}
+		if err := os.Rename(join(op.src), join(op.dst)); err != nil {
+			return err
+		}
+	}
+	for _, op := range creates {
+		if isDir(op.dst) {
+			continue
+		}
+		if err := mkdirParent(op.dst); err != nil {
+			return err
+		}
+		f, err := os.OpenFile(join(op.dst), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err != nil {
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+	}
+	trash := byType(ldoTrash)
+	sort.SliceStable(trash, func(i, j int) bool {
+		return listDirDepth(trash[i].src) < listDirDepth(trash[j].src)
+	})
+	trashed := []string{}
+	for _, op := range trash {
+		name := join(op.src)
+		inTrashed := false
+		for _, d := range trashed {
+			if osutil.FilepathHasDirPrefix(name, d) {
+				inTrashed = true
+				break
+			}
+		}
+		if inTrashed {
+			continue
+		}
+		if err := osutil.MoveToTrash(name); err != nil {
+			return err
+		}
+		trashed = append(trashed, filepath.Clean(name))
+	}
+	return nil
+}
+
+//----------
+
+// Lines with names (the "../" line at the top is ignored).
+func listDirLines(b []byte) []string {
+	u := []string{}
+	for _, l := range strings.Split(string(b), "\n") {
+		l = strings.TrimSpace(l)
+		if l == "" || l == ".."+string(os.PathSeparator) {
+			continue
+		}
+		u = append(u, l)
+	}
+	return u
+}
+
+// Name of the line without escapes. Keeps the ending separator of dirs.
+func listDirLineName(l string) string {
+	return parseutil.RemoveEscapes(strings.TrimSpace(l), osutil.EscapeRune)
+}
+
+func listDirIsSubPath(name string) bool {
+	if filepath.IsAbs(name) {
+		return false
+	}
+	c := filepath.Clean(name)
+	return c != "." && c != ".." && !strings.HasPrefix(c, ".."+string(os.PathSeparator))
+}
+
+func listDirDepth(name string) int {
+	return strings.Count(filepath.Clean(name), string(os.PathSeparator))
+}
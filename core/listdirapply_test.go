@@ -0,0 +1,128 @@
+package core
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestListDirOps(t *testing.T) {
+	old := "../\na/\na/x.txt\nb.txt\nc.txt\nd.txt\n"
+	cur := "../\na/\na/x.txt\na/c.txt\nb2.txt\nd.txt\ne/\nd.txt -> a/d.txt\nf\\ g.txt\n"
+	ops, err := listDirOps(listDirLines([]byte(old)), listDirLines([]byte(cur)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := listDirOpsStr(ops)
+	want := "move,c.txt,a/c.txt;move,b.txt,b2.txt;copy,d.txt,a/d.txt;create,,e/;create,,f g.txt"
+	if s != want {
+		t.Fatalf("\n%v\n%v", s, want)
+	}
+
+	// line edited in place into a copy: the source is kept
+	ops, err = listDirOps([]string{"a.txt", "b.txt"}, []string{"a.txt -> c.txt", "b.txt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := listDirOpsStr(ops); s != "copy,a.txt,c.txt" {
+		t.Fatal(s)
+	}
+
+	// reordered lines: nothing to do
+	ops, err = listDirOps([]string{"a.txt", "b.txt"}, []string{"b.txt", "a.txt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := listDirOpsStr(ops); s != "" {
+		t.Fatal(s)
+	}
+
+	// outside the dir
+	_, err = listDirOps([]string{"a.txt"}, []string{"../a.txt"})
+	if err == nil {
+		t.Fatal("expecting error")
+	}
+}
+
+func listDirOpsStr(ops []*listDirOp) string {
+	u := []string{}
+	for _, op := range ops {
+		u = append(u, strings.Join([]string{
+			[]string{"create", "copy", "move", "trash"}[op.typ],
+			op.src, op.dst,
+		}, ","))
+	}
+	return strings.Join(u, ";")
+}
+
+func TestApplyListDirOps(t *testing.T) {
+	dir, err := ioutil.TempDir("", "listdirapply")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	old := os.Getenv("XDG_DATA_HOME")
+	defer os.Setenv("XDG_DATA_HOME", old)
+	os.Setenv("XDG_DATA_HOME", filepath.Join(dir, "data"))
+
+	root := filepath.Join(dir, "root")
+	for _, name := range []string{"a/x.txt", "b.txt", "c.txt", "d/y.txt"} {
+		p := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	old2 := "../\na/\na/x.txt\nb.txt\nd/\nd/y.txt\nc.txt\n"
+	cur2 := "../\na2/\na/x2.txt\nb.txt\ne/c.txt\nb.txt -> e/b.txt\ne/new.txt\n"
+	ops, err := listDirOps(listDirLines([]byte(old2)), listDirLines([]byte(cur2)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := applyListDirOps(root, ops); err != nil {
+		t.Fatal(err)
+	}
+
+	exist := []string{"a2/x2.txt", "b.txt", "e/b.txt", "e/c.txt", "e/new.txt"}
+	for _, name := range exist {
+		if _, err := os.Stat(filepath.Join(root, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	notExist := []string{"a", "c.txt", "d"}
+	for _, name := range notExist {
+		if _, err := os.Stat(filepath.Join(root, name)); !os.IsNotExist(err) {
+			t.Fatalf("exists: %v", name)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "data", "Trash", "files", "d", "y.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	// destination exists, nothing is done
+	ops, err = listDirOps([]string{"b.txt"}, []string{"b.txt", "e/c.txt -> b.txt"})
+	if err == nil {
+		err = applyListDirOps(root, ops)
+	}
+	if err == nil || !strings.Contains(err.Error(), "exists") {
+		t.Fatal(err)
+	}
+
+	// copy into its own sub directory, nothing is done
+	ops, err = listDirOps([]string{"e/"}, []string{"e/", "e/ -> e/sub/"})
+	if err == nil {
+		err = applyListDirOps(root, ops)
+	}
+	if err == nil || !strings.Contains(err.Error(), "inside") {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "e", "sub")); !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+}
@@ -20,6 +20,7 @@ type LangInstance struct {
 		sw         *ServerWrap
 		connCancel context.CancelFunc
 	}
+	env []string // server env, set at client start (nil: process env)
 }
 
 func NewLangInstance(lang *LangManager) *LangInstance {
@@ -36,6 +37,9 @@ func (li *LangInstance) client(ctx context.Context, filename string) (*Client, e
 		return li.mu.cli, nil
 	}
 	// start new client/server
+	if fn := li.lang.man.EnvFn; fn != nil {
+		li.env = fn(filename)
+	}
 	if err := li.startClientServer(ctx); err != nil {
 		err = li.lang.WrapError(err)
 		return nil, err
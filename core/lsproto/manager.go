@@ -19,6 +19,8 @@ import (
 type Manager struct {
 	langs      []*LangManager
 	asyncErrFn func(error) // called by LangManager
+
+	EnvFn func(filename string) []string // env for servers started for the filename (optional)
 }
 
 func NewManager(asyncErrFn func(error)) *Manager {
@@ -103,6 +103,7 @@ func newServerWrapCommon(ctx0 context.Context, cmd string, li *LangInstance, pre
 	// cmd
 	args := strings.Split(cmd, " ") // TODO: escapes
 	sw.Cmd = osutil.ExecCmdCtxWithAttr(ctx, args)
+	sw.Cmd.Env = li.env
 
 	if preStartFn != nil {
 		if err := preStartFn(sw); err != nil {
@@ -0,0 +1,143 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jmigpin/editor/core/fsys"
+	"github.com/jmigpin/editor/core/parseutil"
+	"github.com/jmigpin/editor/core/toolbarparser"
+	"github.com/jmigpin/editor/util/osutil"
+)
+
+// Acme style cmds that use the row selection. The "|" prefix needs to be escaped in the toolbar since it is the parts separator.
+//
+//	\|cmd: replace the selection with the output of cmd (selection as input)
+//	<cmd: insert the output of cmd at the cursor
+//	>cmd: show the output of cmd in a new row (selection as input)
+type PipeCmdType int
+
+const (
+	PipeCmdNone PipeCmdType = iota
+	PipeCmdReplace
+	PipeCmdInsert
+	PipeCmdOutput
+)
+
+//----------
+
+func pipeCmdPartArgs(part *toolbarparser.Part) (PipeCmdType, []string) {
+	a0 := part.Args[0].Str()
+	prefixes := []struct {
+		s   string
+		typ PipeCmdType
+	}{
+		{string(osutil.EscapeRune) + "|", PipeCmdReplace},
+		{"<", PipeCmdInsert},
+		{">", PipeCmdOutput},
+	}
+	for _, p := range prefixes {
+		if !strings.HasPrefix(a0, p.s) {
+			continue
+		}
+		u := shellCmdPartArgsStr(part)
+		u[0] = parseutil.RemoveEscapesEscapable(a0[len(p.s):], osutil.EscapeRune, "|")
+		if u[0] == "" {
+			u = u[1:] // allow a space after the prefix: "< date"
+		}
+		if len(u) == 0 {
+			return PipeCmdNone, nil
+		}
+		return p.typ, osutil.ShellRunArgs(u...)
+	}
+	return PipeCmdNone, nil
+}
+
+//----------
+
+func PipeCmd(erow *ERow, typ PipeCmdType, cargs []string) error {
+	ta := erow.Row.TextArea
+	tc := ta.TextCursor
+
+	// input
+	s, e := tc.Index(), tc.Index()
+	if typ != PipeCmdInsert && tc.SelectionOn() {
+		s, e = tc.SelectionIndexes()
+	}
+	in, err := tc.RW().ReadNCopyAt(s, e-s)
+	if err != nil {
+		return err
+	}
+
+	if typ == PipeCmdOutput {
+		return pipeCmdOutput(erow, cargs, in)
+	}
+
+	dir := erow.Info.Dir()
+	env := populateEnvVars(erow, cargs)
+	len0 := tc.RW().Max()
+	ctx := erow.ctx
+	go func() {
+		out, err := pipeCmdRun(ctx, dir, in, cargs, env)
+		erow.Ed.UI.RunOnUIGoRoutine(func() {
+			if ctx.Err() != nil {
+				return // row closed
+			}
+			if err != nil {
+				erow.Ed.Errorf("pipecmd: %v", err)
+				return
+			}
+			// the input must be at the same place
+			if tc.RW().Max() != len0 {
+				erow.Ed.Errorf("pipecmd: row changed while running")
+				return
+			}
+			if b, err := tc.RW().ReadNCopyAt(s, e-s); err != nil || !bytes.Equal(b, in) {
+				erow.Ed.Errorf("pipecmd: row changed while running")
+				return
+			}
+			if err := pipeCmdOverwrite(erow, s, e, out); err != nil {
+				erow.Ed.Errorf("pipecmd: %v", err)
+			}
+		})
+	}()
+	return nil
+}
+
+// Runs in the row filesystem (ex: remote host for ssh rows).
+func pipeCmdRun(ctx context.Context, dir string, in []byte, cargs, env []string) ([]byte, error) {
+	cmd := fsys.Command(ctx, dir, cargs, env)
+	cmd.Stdin = bytes.NewReader(in)
+	return osutil.RunExecCmdAndGetStdout(cmd)
+}
+
+// Single undoable edit.
+func pipeCmdOverwrite(erow *ERow, s, e int, out []byte) error {
+	tc := erow.Row.TextArea.TextCursor
+	var err error
+	tc.Edit(func() {
+		err = tc.RW().Overwrite(s, e-s, out)
+		if err != nil {
+			return
+		}
+		// select the output
+		tc.SetSelection(s, s+len(out))
+	})
+	return err
+}
+
+// Runs the cmd in a new dir row below with the input as stdin.
+func pipeCmdOutput(erow *ERow, cargs []string, in []byte) error {
+	if erow.Info.IsSpecial() {
+		return fmt.Errorf("unable to run cmd for erow: %v", erow.Info.Name())
+	}
+	if in == nil {
+		in = []byte{} // not nil: don't read stdin from the row
+	}
+	erow2 := newDirERowBelow(erow)
+	env := populateEnvVars(erow, cargs)
+	externalCmdDirInput(erow2, cargs, nil, env, in)
+	return nil
+}
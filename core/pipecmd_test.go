@@ -0,0 +1,56 @@
+package core
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/jmigpin/editor/core/toolbarparser"
+)
+
+func TestPipeCmdPartArgs(t *testing.T) {
+	for _, name := range []string{"sh", "sort", "tr", "echo"} {
+		if _, err := exec.LookPath(name); err != nil {
+			t.Skip(err)
+		}
+	}
+	dir, err := ioutil.TempDir("", "editor_pipecmd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		s   string
+		typ PipeCmdType
+		in  string
+		out string
+	}{
+		{`\|sort -r`, PipeCmdReplace, "a\nc\nb\n", "c\nb\na\n"},
+		{`\|tr a b \| sort`, PipeCmdReplace, "ca\na\n", "b\ncb\n"},
+		{`<echo a`, PipeCmdInsert, "", "a\n"},
+		{`< echo a`, PipeCmdInsert, "", "a\n"},
+		{`>tr a b`, PipeCmdOutput, "aa", "bb"},
+		{`>`, PipeCmdNone, "", ""},
+		{`Stop`, PipeCmdNone, "", ""},
+	}
+	for _, tt := range tests {
+		data := toolbarparser.Parse(tt.s)
+		typ, cargs := pipeCmdPartArgs(data.Parts[0])
+		if typ != tt.typ {
+			t.Fatalf("%q: expecting type %v, got %v", tt.s, tt.typ, typ)
+		}
+		if typ == PipeCmdNone {
+			continue
+		}
+		out, err := pipeCmdRun(context.Background(), dir, []byte(tt.in), cargs, os.Environ())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != tt.out {
+			t.Fatalf("%q: expecting output %q, got %q", tt.s, tt.out, out)
+		}
+	}
+}
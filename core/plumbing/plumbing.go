@@ -0,0 +1,241 @@
+// Rules that map the clicked text to actions (similar to acme plumbing).
+//
+// Rules file example:
+//
+//	# comments start with "#"
+//	match JIRA-[0-9]+
+//	url https://jira.example.com/browse/$0
+//
+//	match ([^ \t:]+\.go):([0-9]+)
+//	open $1:$2
+//
+//	match \bT([0-9]+)\b
+//	filename /myproject/
+//	ext .go .md
+//	run mytool show $1
+//
+// A rule starts with "match <regexp>", has optional conditions on the row filename ("filename <regexp>", "ext <exts>") and one action ("open", "run" or "url") with an argument that is expanded with the match groups ($0, $1, ${name}). In "run" actions, the groups are shell quoted (the clicked text can't inject shell syntax).
+package plumbing
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jmigpin/editor/util/osutil"
+)
+
+type Rule struct {
+	Match    *regexp.Regexp
+	Filename *regexp.Regexp // optional condition on the row filename
+	Exts     []string       // optional condition on the row filename extension
+	Action   Action
+	Arg      string // expanded with the match groups
+	Line     int    // line in the rules file
+}
+
+func (r *Rule) accepts(filename string) bool {
+	if r.Filename != nil && !r.Filename.MatchString(filename) {
+		return false
+	}
+	if len(r.Exts) > 0 {
+		ext := filepath.Ext(filename)
+		found := false
+		for _, e := range r.Exts {
+			if e == ext {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *Rule) expand(text []byte, m []int) []byte {
+	if r.Action != RunAction {
+		return r.Match.Expand(nil, []byte(r.Arg), text, m)
+	}
+	// expand with the groups quoted
+	quoted := []byte{}
+	m2 := make([]int, len(m))
+	for i := 0; i < len(m); i += 2 {
+		if m[i] < 0 {
+			m2[i], m2[i+1] = -1, -1
+			continue
+		}
+		m2[i] = len(quoted)
+		quoted = append(quoted, osutil.ShellQuote(string(text[m[i]:m[i+1]]))...)
+		m2[i+1] = len(quoted)
+	}
+	return r.Match.Expand(nil, []byte(r.Arg), quoted, m2)
+}
+
+//----------
+
+type Action int
+
+const (
+	OpenAction Action = iota // open filename (with optional position)
+	RunAction                // run external cmd
+	URLAction                // open url in preferred application
+)
+
+var actionNames = map[string]Action{
+	"open": OpenAction,
+	"run":  RunAction,
+	"url":  URLAction,
+}
+
+//----------
+
+type Result struct {
+	Rule       *Rule
+	Arg        string // expanded
+	Start, End int    // match position in the text
+}
+
+// Returns the result of the first rule that matches the text containing the index. The filename is the row name used in the rules conditions.
+func Match(rules []*Rule, text []byte, index int, filename string) (*Result, bool) {
+	for _, r := range rules {
+		if !r.accepts(filename) {
+			continue
+		}
+		for _, m := range r.Match.FindAllSubmatchIndex(text, -1) {
+			if index < m[0] || index > m[1] {
+				continue
+			}
+			arg := r.expand(text, m)
+			res := &Result{Rule: r, Arg: string(arg), Start: m[0], End: m[1]}
+			return res, true
+		}
+	}
+	return nil, false
+}
+
+//----------
+
+func ParseRules(src []byte) ([]*Rule, error) {
+	rules := []*Rule{}
+	var r *Rule
+	endRule := func() error {
+		if r != nil && r.Arg == "" {
+			return fmt.Errorf("line %v: rule without action", r.Line)
+		}
+		r = nil
+		return nil
+	}
+
+	sc := bufio.NewScanner(bytes.NewReader(src))
+	for line := 1; sc.Scan(); line++ {
+		s := strings.TrimSpace(sc.Text())
+		if s == "" || strings.HasPrefix(s, "#") {
+			continue
+		}
+		key, val := s, ""
+		if i := strings.IndexAny(s, " \t"); i >= 0 {
+			key, val = s[:i], strings.TrimSpace(s[i+1:])
+		}
+		if val == "" {
+			return nil, fmt.Errorf("line %v: missing value: %q", line, key)
+		}
+
+		if key == "match" {
+			if err := endRule(); err != nil {
+				return nil, err
+			}
+			re, err := regexp.Compile(val)
+			if err != nil {
+				return nil, fmt.Errorf("line %v: %v", line, err)
+			}
+			r = &Rule{Match: re, Line: line}
+			rules = append(rules, r)
+			continue
+		}
+
+		if r == nil {
+			return nil, fmt.Errorf("line %v: expecting \"match\": %q", line, key)
+		}
+		if r.Arg != "" {
+			return nil, fmt.Errorf("line %v: rule already has an action", line)
+		}
+		switch key {
+		case "filename":
+			re, err := regexp.Compile(val)
+			if err != nil {
+				return nil, fmt.Errorf("line %v: %v", line, err)
+			}
+			r.Filename = re
+		case "ext":
+			r.Exts = strings.Fields(val)
+		default:
+			a, ok := actionNames[key]
+			if !ok {
+				return nil, fmt.Errorf("line %v: unknown key: %q", line, key)
+			}
+			r.Action, r.Arg = a, val
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if err := endRule(); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+//----------
+
+// Rules file that is read again if it was modified. Safe to use concurrently.
+type RulesFile struct {
+	Filename string
+
+	mu      sync.Mutex
+	modTime time.Time
+	rules   []*Rule
+	err     error
+}
+
+func NewRulesFile(filename string) *RulesFile {
+	return &RulesFile{Filename: filename}
+}
+
+// Returns no rules if the file doesn't exist.
+func (rf *RulesFile) Rules() ([]*Rule, error) {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	fi, err := os.Stat(rf.Filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			rf.modTime, rf.rules, rf.err = time.Time{}, nil, nil
+			return nil, nil
+		}
+		return nil, err
+	}
+	if fi.ModTime().Equal(rf.modTime) {
+		return rf.rules, rf.err
+	}
+
+	rf.modTime = fi.ModTime()
+	b, err := ioutil.ReadFile(rf.Filename)
+	if err != nil {
+		rf.rules, rf.err = nil, err
+	} else {
+		rf.rules, rf.err = ParseRules(b)
+		if rf.err != nil {
+			rf.err = fmt.Errorf("%v: %v", rf.Filename, rf.err)
+		}
+	}
+	return rf.rules, rf.err
+}
@@ -0,0 +1,71 @@
+package plumbing
+
+import (
+	"testing"
+)
+
+func TestPlumbing1(t *testing.T) {
+	src := `
+# tickets
+match JIRA-[0-9]+
+url https://jira.example.com/browse/$0
+
+match ([^ \t:]+\.go):([0-9]+)
+ext .txt .log
+open $1:$2
+
+match ([^ \t:]+\.go):([0-9]+)
+run echo ${1}
+`
+	rules, err := ParseRules([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 3 {
+		t.Fatalf("rules: %v", len(rules))
+	}
+
+	text := []byte("see JIRA-123 at main.go:12")
+
+	// index inside the ticket
+	res, ok := Match(rules, text, 6, "/a/b.txt")
+	if !ok || res.Rule.Action != URLAction || res.Arg != "https://jira.example.com/browse/JIRA-123" {
+		t.Fatalf("%+v", res)
+	}
+
+	// index outside of any match
+	if _, ok := Match(rules, text, 1, "/a/b.txt"); ok {
+		t.Fatal("unexpected match")
+	}
+
+	// ext condition
+	res, ok = Match(rules, text, 20, "/a/b.log")
+	if !ok || res.Rule.Action != OpenAction || res.Arg != "main.go:12" {
+		t.Fatalf("%+v", res)
+	}
+	res, ok = Match(rules, text, 20, "/a/b.go")
+	if !ok || res.Rule.Action != RunAction || res.Arg != "echo 'main.go'" {
+		t.Fatalf("%+v", res)
+	}
+
+	// run groups are quoted
+	text2 := []byte("$(ls);'a.go:1")
+	res, ok = Match(rules, text2, 2, "/a/b.go")
+	if !ok || res.Arg != `echo '$(ls);'\''a.go'` {
+		t.Fatalf("%+v", res)
+	}
+}
+
+func TestPlumbing2(t *testing.T) {
+	for _, src := range []string{
+		"open a.go",               // missing match
+		"match a\nmatch b\nrun c", // rule without action
+		"match a\nrun b\nurl c",   // two actions
+		"match a\nabc d",          // unknown key
+		"match (a\nrun b",         // bad regexp
+	} {
+		if _, err := ParseRules([]byte(src)); err == nil {
+			t.Fatalf("expecting error: %q", src)
+		}
+	}
+}
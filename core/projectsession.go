@@ -0,0 +1,216 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/jmigpin/editor/core/toolbarparser"
+	"github.com/jmigpin/editor/util/osutil"
+)
+
+// Sessions file that lives in a project directory (ex: committed with the code). Paths under the project directory are stored relative to it, such that the sessions work wherever the project is.
+const ProjectSessionsFilename = ".editor_session.json"
+
+// Returns the project sessions file found in dir or its parents.
+func findProjectSessionsFile(dir string) (string, bool) {
+	for d := dir; d != ""; {
+		filename := filepath.Join(d, ProjectSessionsFilename)
+		if _, err := os.Stat(filename); err == nil {
+			return filename, true
+		}
+		d2 := filepath.Dir(d)
+		if d2 == d {
+			break
+		}
+		d = d2
+	}
+	return "", false
+}
+
+// Project sessions file found from the current directory.
+func projectSessionsFilename() (string, bool) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", false
+	}
+	return findProjectSessionsFile(dir)
+}
+
+//----------
+
+// Returns a copy with the paths under root stored relative: the home vars values are made relative to root (ex: "~1=./a/b"), a home var is added for root if needed ("~0=."), and the rows names are encoded with it ("~0/a/b.txt").
+func sessionToProject(s *Session, root string) *Session {
+	s2 := *s
+
+	// home var that points to root
+	rootVar := ""
+	used := map[string]bool{}
+	mapToolbarHomeVars(s.RootTbStr, func(name, value string) string {
+		used[name] = true
+		if rootVar == "" && filepath.Clean(value) == root {
+			rootVar = name
+		}
+		return value
+	})
+	tbStr := s.RootTbStr
+	if rootVar == "" {
+		for i := 0; ; i++ {
+			rootVar = "~" + strconv.Itoa(i)
+			if !used[rootVar] {
+				break
+			}
+		}
+		tbStr = strings.TrimRight(tbStr, " |") + " | " + rootVar + "=" + root
+	}
+
+	s2.RootTbStr = mapToolbarHomeVars(tbStr, func(name, value string) string {
+		return projectRelPath(root, value)
+	})
+
+	// rows names
+	s2.Columns = nil
+	for _, c := range s.Columns {
+		c2 := *c
+		c2.Rows = nil
+		for _, rs := range c.Rows {
+			rs2 := *rs
+			rs2.TbStr = mapToolbarName(rs.TbStr, func(name string) string {
+				if name == root {
+					return rootVar
+				}
+				if osutil.FilepathHasDirPrefix(name, root) {
+					return filepath.Join(rootVar, name[len(root):])
+				}
+				return name
+			})
+			c2.Rows = append(c2.Rows, &rs2)
+		}
+		s2.Columns = append(s2.Columns, &c2)
+	}
+	return &s2
+}
+
+// Returns a copy with the relative home vars values joined with root. The rows names are decoded with the home vars when opened.
+// The env profiles vars are removed: the project file can come from anywhere (ex: cloned repository), and would change the environment of the cmds without being allowed (see AllowEnv).
+func sessionFromProject(s *Session, root string) *Session {
+	s2 := *s
+	s2.RootTbStr = mapToolbarHomeVars(s.RootTbStr, func(name, value string) string {
+		if value == "." || strings.HasPrefix(value, "./") {
+			return filepath.Join(root, value)
+		}
+		return value
+	})
+	s2.RootTbStr = removeToolbarEnvVars(s2.RootTbStr)
+	s2.MenuTbStr = removeToolbarEnvVars(s.MenuTbStr)
+	return &s2
+}
+
+func projectRelPath(root, value string) string {
+	if value == root {
+		return "."
+	}
+	if osutil.FilepathHasDirPrefix(value, root) {
+		return "./" + filepath.ToSlash(value[len(root)+1:])
+	}
+	return value
+}
+
+//----------
+
+// Replaces the values of the "~N=value" vars in a toolbar string.
+func mapToolbarHomeVars(str string, fn func(name, value string) string) string {
+	data := toolbarparser.Parse(str)
+	for i := len(data.Parts) - 1; i >= 0; i-- {
+		part := data.Parts[i]
+		if len(part.Args) != 1 {
+			continue
+		}
+		arg := part.Args[0]
+		v, err := toolbarparser.ParseVar(arg.Str())
+		if err != nil || !strings.HasPrefix(v.Name, "~") {
+			continue
+		}
+		value := fn(v.Name, v.Value)
+		if value == v.Value {
+			continue
+		}
+		if strings.ContainsAny(value, " \t|") {
+			value = strconv.Quote(value)
+		}
+		str = str[:arg.Pos] + v.Name + "=" + value + str[arg.End:]
+	}
+	return str
+}
+
+// Removes the parts with the "$env" and "$env_<name>" vars from a toolbar string.
+func removeToolbarEnvVars(str string) string {
+	data := toolbarparser.Parse(str)
+	removed := false
+	for i := len(data.Parts) - 1; i >= 0; i-- {
+		part := data.Parts[i]
+		if len(part.Args) != 1 {
+			continue
+		}
+		v, err := toolbarparser.ParseVar(part.Args[0].Str())
+		if err != nil || !(v.Name == "$env" || strings.HasPrefix(v.Name, "$env_")) {
+			continue
+		}
+		// remove with a separator
+		pos, end := part.Pos, part.End
+		if end < len(str) {
+			end++
+		} else if pos > 0 {
+			pos--
+		}
+		str = str[:pos] + str[end:]
+		removed = true
+	}
+	if removed {
+		str = strings.TrimSpace(str)
+	}
+	return str
+}
+
+// Replaces the name (first arg) of a row toolbar string.
+func mapToolbarName(str string, fn func(string) string) string {
+	data := toolbarparser.Parse(str)
+	arg0, ok := data.Part0Arg0()
+	if !ok {
+		return str
+	}
+	return fn(arg0.Str()) + str[arg0.End:]
+}
+
+//----------
+
+func SaveProjectSession(ed *Editor, part *toolbarparser.Part) {
+	if err := saveProjectSession(ed, part); err != nil {
+		ed.Error(err)
+	}
+}
+func saveProjectSession(ed *Editor, part *toolbarparser.Part) error {
+	s, err := newSessionFromCmd(ed, part)
+	if err != nil {
+		return fmt.Errorf("saveprojectsession: %v", err)
+	}
+
+	// project file found from the current directory, or a new one in the current directory
+	filename, ok := projectSessionsFilename()
+	if !ok {
+		dir, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		filename = filepath.Join(dir, ProjectSessionsFilename)
+	}
+	root := filepath.Dir(filename)
+
+	if err := saveSession2(sessionToProject(s, root), filename); err != nil {
+		return err
+	}
+	ed.Messagef("saved project session %q: %v", s.Name, filename)
+	return nil
+}
@@ -0,0 +1,49 @@
+package core
+
+import (
+	"testing"
+)
+
+func TestProjectSession1(t *testing.T) {
+	root := "/a/proj"
+	s := &Session{
+		Name:      "s1",
+		RootTbStr: "Exit | ~0=/a/proj/core | ~1=/b",
+		Columns: []*ColumnState{
+			{Rows: []*RowState{
+				{TbStr: "/a/proj/core/editor.go | Save"},
+				{TbStr: "/a/proj"},
+				{TbStr: "/b/c.txt"},
+			}},
+		},
+	}
+
+	s2 := sessionToProject(s, root)
+	if s2.RootTbStr != "Exit | ~0=./core | ~1=/b | ~2=." {
+		t.Fatalf("%q", s2.RootTbStr)
+	}
+	rows := s2.Columns[0].Rows
+	if rows[0].TbStr != "~2/core/editor.go | Save" || rows[1].TbStr != "~2" || rows[2].TbStr != "/b/c.txt" {
+		t.Fatalf("%q %q %q", rows[0].TbStr, rows[1].TbStr, rows[2].TbStr)
+	}
+	// original not changed
+	if s.Columns[0].Rows[0].TbStr != "/a/proj/core/editor.go | Save" {
+		t.Fatal(s.Columns[0].Rows[0].TbStr)
+	}
+
+	s3 := sessionFromProject(s2, "/c/proj2")
+	if s3.RootTbStr != "Exit | ~0=/c/proj2/core | ~1=/b | ~2=/c/proj2" {
+		t.Fatalf("%q", s3.RootTbStr)
+	}
+}
+
+func TestProjectSessionEnvVars(t *testing.T) {
+	s := &Session{Name: "s1", RootTbStr: `Exit | $env_a="PATH=/x" | ~0=./core |$env=a`, MenuTbStr: `$env_b="PATH=/y" | GoDebug run`}
+	s2 := sessionFromProject(s, "/c/proj")
+	if s2.RootTbStr != "Exit | ~0=/c/proj/core" {
+		t.Fatalf("%q", s2.RootTbStr)
+	}
+	if s2.MenuTbStr != "GoDebug run" {
+		t.Fatalf("%q", s2.MenuTbStr)
+	}
+}
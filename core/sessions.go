@@ -38,16 +38,24 @@ func NewSessions(filename string) (*Sessions, error) {
 	}
 	return ss, err
 }
+func (ss *Sessions) find(name string) (*Session, bool) {
+	for _, s := range ss.Sessions {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return nil, false
+}
+
+// Atomic write: the file is not left truncated if the editor is killed while saving (autosave).
 func (ss *Sessions) save(filename string) error {
-	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
-	f, err := os.OpenFile(filename, flags, 0644)
-	if err != nil {
+	buf := &bytes.Buffer{}
+	enc := json.NewEncoder(buf)
+	enc.SetIndent("", "    ")
+	if err := enc.Encode(&ss); err != nil {
 		return err
 	}
-	defer f.Close()
-	enc := json.NewEncoder(f)
-	enc.SetIndent("", "    ")
-	return enc.Encode(&ss)
+	return osutil.WriteFileAtomic(filename, buf.Bytes(), 0644, false)
 }
 
 //----------
@@ -62,12 +70,14 @@ func sessionsFilename() string {
 type Session struct {
 	Name      string
 	RootTbStr string
+	MenuTbStr string `json:",omitempty"` // main menu toolbar (ex: GoDebug cmds, vars)
 	Columns   []*ColumnState
 }
 
 func NewSessionFromEditor(ed *Editor) *Session {
 	s := &Session{
 		RootTbStr: ed.UI.Root.Toolbar.Str(),
+		MenuTbStr: ed.UI.Root.MainMenuButton.Toolbar.Str(),
 	}
 	for _, c := range ed.UI.Root.Cols.Columns() {
 		cstate := NewColumnState(ed, c)
@@ -75,7 +85,25 @@ func NewSessionFromEditor(ed *Editor) *Session {
 	}
 	return s
 }
-func (s *Session) restore(ed *Editor) {
+
+// Keeps the unsaved files contents and the cmd rows output.
+func (s *Session) addContents(ed *Editor) error {
+	for i, c := range ed.UI.Root.Cols.Columns() {
+		for j, row := range c.Rows() {
+			erow, ok := ed.rowERow(row)
+			if !ok {
+				continue
+			}
+			if err := s.Columns[i].Rows[j].setContent(erow); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// If rerun is true, the rows cmds are run again, otherwise the saved output is shown and the cmd can be run with Rerun.
+func (s *Session) restore(ed *Editor, rerun bool) {
 	uicols := ed.UI.Root.Cols
 
 	// layout toolbar
@@ -83,6 +111,10 @@ func (s *Session) restore(ed *Editor) {
 
 	ed.UI.Root.Toolbar.SetStrClearHistory(tbStr)
 
+	if s.MenuTbStr != "" {
+		ed.UI.Root.MainMenuButton.Toolbar.SetStrClearHistory(s.MenuTbStr)
+	}
+
 	// close all current columns
 	for _, c := range uicols.Columns() {
 		c.Close()
@@ -124,8 +156,11 @@ func (s *Session) restore(ed *Editor) {
 		}
 	}
 
-	// restore positions after all rows have been created
+	// restore contents and positions after all rows have been created
 	for rs, erow := range m {
+		if err := rs.restoreContent(erow, rerun); err != nil {
+			ed.Error(err)
+		}
 		rs.RestorePos(erow)
 	}
 }
@@ -157,6 +192,12 @@ type RowState struct {
 	TaCursorIndex int
 	TaOffsetIndex int
 	StartPercent  float64
+
+	TaSelectionOn    bool `json:",omitempty"`
+	TaSelectionIndex int  `json:",omitempty"`
+
+	Cmd     []string `json:",omitempty"` // last cmd run in the row
+	Content []byte   `json:",omitempty"` // unsaved file content, or cmd output (optional)
 }
 
 func NewRowState(ed *Editor, row *ui.Row) *RowState {
@@ -176,6 +217,13 @@ func NewRowState(ed *Editor, row *ui.Row) *RowState {
 		TaCursorIndex: row.TextArea.TextCursor.Index(),
 		TaOffsetIndex: row.TextArea.RuneOffset(),
 	}
+	if tc := row.TextArea.TextCursor; tc.SelectionOn() {
+		rs.TaSelectionOn = true
+		rs.TaSelectionIndex = tc.SelectionIndex()
+	}
+	if erow, ok := ed.rowERow(row); ok {
+		rs.Cmd = erow.Exec.rerunArgs
+	}
 
 	// check row.col in case the row has been removed from columns (reopenrow?)
 	if row.Col != nil {
@@ -217,12 +265,81 @@ func (state *RowState) OpenERow(ed *Editor, rowPos *ui.RowPos) (*ERow, bool, err
 
 func (state *RowState) RestorePos(erow *ERow) {
 	erow.Row.Toolbar.TextCursor.SetIndex(state.TbCursorIndex)
-	erow.Row.TextArea.TextCursor.SetIndex(state.TaCursorIndex)
+	tc := erow.Row.TextArea.TextCursor
+	if state.TaSelectionOn {
+		tc.SetSelection(state.TaSelectionIndex, state.TaCursorIndex)
+	} else {
+		tc.SetIndex(state.TaCursorIndex)
+	}
 	erow.Row.TextArea.SetRuneOffset(state.TaOffsetIndex)
 }
 
 //----------
 
+// Max size of a cmd output saved in a session (keeps the end).
+const sessionMaxOutputSize = 256 * 1024
+
+func (state *RowState) setContent(erow *ERow) error {
+	isFile := erow.Info.IsFileButNotDir()
+	if isFile && !erow.Row.HasState(ui.RowStateEdited) {
+		return nil
+	}
+	if !isFile && state.Cmd == nil {
+		return nil
+	}
+	b, err := erow.Row.TextArea.Bytes()
+	if err != nil {
+		return err
+	}
+	if !isFile && len(b) > sessionMaxOutputSize {
+		b = b[len(b)-sessionMaxOutputSize:]
+		if i := bytes.IndexByte(b, '\n'); i >= 0 {
+			b = b[i+1:]
+		}
+	}
+	state.Content = b
+	return nil
+}
+
+func (state *RowState) restoreContent(erow *ERow, rerun bool) error {
+	if erow.Info.IsFileButNotDir() {
+		if state.Content == nil {
+			return nil
+		}
+		// undoable, row shows as edited
+		return erow.Row.TextArea.SetBytes(state.Content)
+	}
+
+	if state.Cmd == nil {
+		return nil
+	}
+	args := state.Cmd
+	run := func() {
+		runSessionCmd(erow, args)
+	}
+	if rerun {
+		run()
+		return nil
+	}
+	erow.Exec.setRerun(args, run)
+	if state.Content != nil {
+		erow.Row.TextArea.SetBytesClearHistory(state.Content)
+	}
+	return nil
+}
+
+func runSessionCmd(erow *ERow, args []string) {
+	if len(args) > 0 && args[0] == "GoDebug" {
+		if err := erow.Ed.GoDebug.Start(erow, args); err != nil {
+			erow.Ed.Error(err)
+		}
+		return
+	}
+	ExternalCmdFromArgs(erow, args, nil)
+}
+
+//----------
+
 func SaveSession(ed *Editor, part *toolbarparser.Part) {
 	err := saveSession(ed, part, sessionsFilename())
 	if err != nil {
@@ -230,14 +347,61 @@ func SaveSession(ed *Editor, part *toolbarparser.Part) {
 	}
 }
 func saveSession(ed *Editor, part *toolbarparser.Part, filename string) error {
-	if len(part.Args) != 2 {
-		return fmt.Errorf("savesession: missing session name")
+	s1, err := newSessionFromCmd(ed, part)
+	if err != nil {
+		return fmt.Errorf("savesession: %v", err)
 	}
-	sessionName := part.Args[1].Str()
+	return saveSession2(s1, filename)
+}
 
-	s1 := NewSessionFromEditor(ed)
-	s1.Name = sessionName
+// Parses "SaveSession [-contents] <name>".
+func newSessionFromCmd(ed *Editor, part *toolbarparser.Part) (*Session, error) {
+	name, flags, err := sessionCmdArgs(part, "-contents")
+	if err != nil {
+		return nil, err
+	}
+	s := NewSessionFromEditor(ed)
+	s.Name = name
+	if flags["-contents"] {
+		if err := s.addContents(ed); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
 
+// Parses "<cmd> [flags] <name>" with the flags limited to the given ones.
+func sessionCmdArgs(part *toolbarparser.Part, flags ...string) (string, map[string]bool, error) {
+	m := map[string]bool{}
+	name := ""
+	for _, a := range part.Args[1:] {
+		s := a.UnquotedStr()
+		if strings.HasPrefix(s, "-") {
+			found := false
+			for _, f := range flags {
+				if s == f {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return "", nil, fmt.Errorf("unknown flag: %v", s)
+			}
+			m[s] = true
+			continue
+		}
+		if name != "" {
+			return "", nil, fmt.Errorf("unexpected arg: %v", s)
+		}
+		name = s
+	}
+	if name == "" {
+		return "", nil, fmt.Errorf("missing session name")
+	}
+	return name, m, nil
+}
+func saveSession2(s1 *Session, filename string) error {
+	sessionName := s1.Name
 	ss, err := NewSessions(filename)
 	if err != nil {
 		return err
@@ -266,21 +430,36 @@ func saveSession(ed *Editor, part *toolbarparser.Part, filename string) error {
 //----------
 
 func ListSessions(ed *Editor) {
+	buf := &bytes.Buffer{}
+
+	// project sessions
+	if filename, ok := projectSessionsFilename(); ok {
+		ss, err := NewSessions(filename)
+		if err != nil {
+			ed.Error(err)
+			return
+		}
+		u := sessionsNames(ss)
+		fmt.Fprintf(buf, "project sessions: %d (%v)\n", len(u), filename)
+		for _, sname := range u {
+			fmt.Fprintf(buf, "OpenSession %v\n", sname)
+		}
+		fmt.Fprintf(buf, "\n")
+	}
+
 	ss, err := NewSessions(sessionsFilename())
 	if err != nil {
 		ed.Error(err)
 		return
 	}
-
-	// sort sessions names
-	var u []string
-	for _, session := range ss.Sessions {
-		u = append(u, session.Name)
+	u := sessionsNames(ss)
+	if _, ok, _ := latestAutoSaveSession(""); ok {
+		if _, ok := ss.find(AutoSaveSessionName); !ok {
+			u = append([]string{AutoSaveSessionName}, u...)
+		}
 	}
-	sort.Strings(u)
 
 	// concat opensession lines
-	buf := &bytes.Buffer{}
 	fmt.Fprintf(buf, "sessions: %d\n", len(u))
 	for _, sname := range u {
 		fmt.Fprintf(buf, "OpenSession %v\n", sname)
@@ -291,27 +470,68 @@ func ListSessions(ed *Editor) {
 	erow.Flash()
 }
 
+// Sorted names.
+func sessionsNames(ss *Sessions) []string {
+	var u []string
+	for _, session := range ss.Sessions {
+		u = append(u, session.Name)
+	}
+	sort.Strings(u)
+	return u
+}
+
 //----------
 
 func OpenSession(ed *Editor, part *toolbarparser.Part) {
-	if len(part.Args) != 2 {
-		ed.Errorf("missing session name")
+	name, flags, err := sessionCmdArgs(part, "-rerun")
+	if err != nil {
+		ed.Errorf("opensession: %v", err)
 		return
 	}
-	sessionName := part.Args[1].Str()
-	OpenSessionFromString(ed, sessionName)
+	openSession(ed, name, flags["-rerun"])
 }
 
+// Looks for the session in the project sessions file (found from the current directory) first.
 func OpenSessionFromString(ed *Editor, sessionName string) {
+	openSession(ed, sessionName, false)
+}
+
+func openSession(ed *Editor, sessionName string, rerun bool) {
+	if filename, ok := projectSessionsFilename(); ok {
+		ss, err := NewSessions(filename)
+		if err != nil {
+			ed.Error(err)
+			return
+		}
+		if s, ok := ss.find(sessionName); ok {
+			sessionFromProject(s, filepath.Dir(filename)).restore(ed, rerun)
+			return
+		}
+	}
+
+	if sessionName == AutoSaveSessionName {
+		exclude := ""
+		if ed.AutoSave != nil {
+			exclude = ed.AutoSave.filename
+		}
+		s, ok, err := latestAutoSaveSession(exclude)
+		if err != nil {
+			ed.Error(err)
+			return
+		}
+		if ok {
+			s.restore(ed, rerun)
+			return
+		}
+	}
+
 	ss, err := NewSessions(sessionsFilename())
 	if err != nil {
 		return
 	}
-	for _, s := range ss.Sessions {
-		if s.Name == sessionName {
-			s.restore(ed)
-			return
-		}
+	if s, ok := ss.find(sessionName); ok {
+		s.restore(ed, rerun)
+		return
 	}
 	ed.Errorf("session not found: %v", sessionName)
 }
@@ -329,7 +549,20 @@ func deleteSession(ed *Editor, part *toolbarparser.Part) error {
 		return fmt.Errorf("deletesession: missing session name")
 	}
 	sessionName := part.Args[1].Str()
-	ss, err := NewSessions(sessionsFilename())
+
+	// project sessions file first
+	filename := sessionsFilename()
+	if f, ok := projectSessionsFilename(); ok {
+		ss, err := NewSessions(f)
+		if err != nil {
+			return err
+		}
+		if _, ok := ss.find(sessionName); ok {
+			filename = f
+		}
+	}
+
+	ss, err := NewSessions(filename)
 	if err != nil {
 		return err
 	}
@@ -345,5 +578,5 @@ func deleteSession(ed *Editor, part *toolbarparser.Part) error {
 	if !found {
 		return fmt.Errorf("deletesession: session not found: %v", sessionName)
 	}
-	return ss.save(sessionsFilename())
+	return ss.save(filename)
 }
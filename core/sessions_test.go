@@ -0,0 +1,66 @@
+package core
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/jmigpin/editor/core/toolbarparser"
+)
+
+func TestSessionCmdArgs(t *testing.T) {
+	parse := func(s string) (string, map[string]bool, error) {
+		data := toolbarparser.Parse(s)
+		return sessionCmdArgs(data.Parts[0], "-contents")
+	}
+
+	name, flags, err := parse("SaveSession -contents s1")
+	if err != nil || name != "s1" || !flags["-contents"] {
+		t.Fatal(name, flags, err)
+	}
+	name, flags, err = parse("SaveSession s1")
+	if err != nil || name != "s1" || flags["-contents"] {
+		t.Fatal(name, flags, err)
+	}
+	if _, _, err := parse("SaveSession -contents"); err == nil {
+		t.Fatal("expecting missing name error")
+	}
+	if _, _, err := parse("SaveSession -rerun s1"); err == nil {
+		t.Fatal("expecting unknown flag error")
+	}
+	if _, _, err := parse("SaveSession s1 s2"); err == nil {
+		t.Fatal("expecting unexpected arg error")
+	}
+}
+
+func TestRowStateJson(t *testing.T) {
+	// sessions saved before the optional fields
+	src := `{"TbStr":"/a/b.txt","TbCursorIndex":0,"TaCursorIndex":3,"TaOffsetIndex":0,"StartPercent":0.5}`
+	rs := &RowState{}
+	if err := json.Unmarshal([]byte(src), rs); err != nil {
+		t.Fatal(err)
+	}
+	if rs.TaSelectionOn || rs.Cmd != nil || rs.Content != nil {
+		t.Fatal(rs)
+	}
+	b, err := json.Marshal(rs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != src {
+		t.Fatal(string(b))
+	}
+
+	rs.Cmd = []string{"sh", "-c", "make"}
+	rs.Content = []byte("output\n")
+	b, err = json.Marshal(rs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs2 := &RowState{}
+	if err := json.Unmarshal(b, rs2); err != nil {
+		t.Fatal(err)
+	}
+	if len(rs2.Cmd) != 3 || rs2.Cmd[2] != "make" || string(rs2.Content) != "output\n" {
+		t.Fatal(rs2)
+	}
+}
@@ -34,6 +34,8 @@ func main() {
 	flag.StringVar(&opt.SessionName, "sn", "", "open existing session")
 	flag.StringVar(&opt.SessionName, "sessionname", "", "open existing session")
 	flag.BoolVar(&opt.UseMultiKey, "usemultikey", false, "use multi-key to compose characters (Ex: [multi-key, ~, a] = ã)")
+	flag.BoolVar(&opt.AutoReload, "autoreload", false, "reload rows without edits when the file changes on disk (can be set per row with the $autoreload var)")
+	flag.BoolVar(&opt.SaveBackups, "savebackups", false, "on save, keep the previous file content in \"filename~\"")
 	flag.StringVar(&opt.Plugins, "plugins", "", "comma separated string of plugin filenames")
 	flag.Var(&opt.LSProtos, "lsproto", "Language-server-protocol register options. Can be specified multiple times.\nFormat: language,extensions,network{tcp,tcpclient,stdio},cmd,optional{stderr}\nExamples:\n"+lsproto.RegistrationExamples())
 	cpuProfileFlag := flag.String("cpuprofile", "", "profile cpu filename")
@@ -190,6 +190,9 @@ func (row *Row) SetState(s RowState, v bool) {
 func (row *Row) HasState(s RowState) bool {
 	return row.Toolbar.Square.HasState(s)
 }
+func (row *Row) State() RowState {
+	return row.Toolbar.Square.state
+}
 
 //----------
 
@@ -2,6 +2,7 @@ package ui
 
 import (
 	"image"
+	"strings"
 
 	"github.com/jmigpin/editor/util/imageutil"
 	"github.com/jmigpin/editor/util/uiutil/event"
@@ -35,6 +36,12 @@ func (sq *RowSquare) Paint() {
 	if sq.state.has(RowStateNotExist) {
 		bg = sq.TreeThemePaletteColor("rs_not_exist")
 	}
+	if sq.state.has(RowStateExecSuccess) {
+		bg = sq.TreeThemePaletteColor("rs_exec_success")
+	}
+	if sq.state.has(RowStateExecFailure) {
+		bg = sq.TreeThemePaletteColor("rs_exec_failure")
+	}
 	if sq.state.has(RowStateExecuting) {
 		bg = sq.TreeThemePaletteColor("rs_executing")
 	}
@@ -134,6 +141,16 @@ func (m *RowState) modify(u RowState, v bool) {
 	}
 }
 
+func (m RowState) String() string {
+	u := []string{}
+	for i, name := range rowStateNames {
+		if m.has(1 << uint(i)) {
+			u = append(u, name)
+		}
+	}
+	return strings.Join(u, "|")
+}
+
 const (
 	RowStateActive RowState = 1 << iota
 	RowStateExecuting
@@ -144,4 +161,20 @@ const (
 	RowStateDuplicateHighlight
 	RowStateAnnotations
 	RowStateAnnotationsEdited
+	RowStateExecSuccess
+	RowStateExecFailure
 )
+
+var rowStateNames = []string{
+	"active",
+	"executing",
+	"edited",
+	"fsdiffer",
+	"notexist",
+	"duplicate",
+	"duplicatehighlight",
+	"annotations",
+	"annotationsedited",
+	"execsuccess",
+	"execfailure",
+}
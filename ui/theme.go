@@ -197,6 +197,8 @@ func rowSquarePalette() widget.Palette {
 	pal := widget.Palette{
 		"rs_active":              cint(0x0),
 		"rs_executing":           color.RGBA{15, 173, 0, 255},        // dark green
+		"rs_exec_success":        color.RGBA{0, 128, 128, 255},       // teal
+		"rs_exec_failure":        color.RGBA{220, 20, 60, 255},       // crimson
 		"rs_edited":              color.RGBA{0, 0, 255, 255},         // blue
 		"rs_disk_changes":        color.RGBA{255, 0, 0, 255},         // red
 		"rs_not_exist":           color.RGBA{255, 153, 0, 255},       // orange
@@ -0,0 +1,288 @@
+// Line based diff (myers algorithm).
+package diffutil
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/jmigpin/editor/util/mathutil"
+)
+
+type EditType int
+
+const (
+	EqualEdit EditType = iota
+	InsertEdit
+	DeleteEdit
+)
+
+func (t EditType) String() string {
+	switch t {
+	case EqualEdit:
+		return "equal"
+	case InsertEdit:
+		return "insert"
+	case DeleteEdit:
+		return "delete"
+	}
+	return "?"
+}
+
+// Lines a[A:A+N] (equal, delete) or b[B:B+N] (equal, insert).
+type Edit struct {
+	Type EditType
+	A, B int
+	N    int
+}
+
+//----------
+
+// Differences beyond this value are reported as a single delete/insert of the differing lines (bounds memory usage).
+var MaxEditDistance = 2000
+
+// Edits to transform a into b.
+func Diff(a, b []string) []*Edit {
+	edits := []*Edit{}
+	add := func(t EditType, i, j, n int) {
+		if n == 0 {
+			return
+		}
+		if len(edits) > 0 {
+			e := edits[len(edits)-1]
+			if e.Type == t && e.A+editLenA(e) == i && e.B+editLenB(e) == j {
+				e.N += n
+				return
+			}
+		}
+		edits = append(edits, &Edit{Type: t, A: i, B: j, N: n})
+	}
+
+	// common prefix/suffix
+	p := 0
+	for p < len(a) && p < len(b) && a[p] == b[p] {
+		p++
+	}
+	s := 0
+	for s < len(a)-p && s < len(b)-p && a[len(a)-1-s] == b[len(b)-1-s] {
+		s++
+	}
+
+	add(EqualEdit, 0, 0, p)
+	a2, b2 := a[p:len(a)-s], b[p:len(b)-s]
+	if !myers(a2, b2, p, p, add) {
+		add(DeleteEdit, p, p, len(a2))
+		add(InsertEdit, p+len(a2), p, len(b2))
+	}
+	add(EqualEdit, len(a)-s, len(b)-s, s)
+	return edits
+}
+
+func editLenA(e *Edit) int {
+	if e.Type == InsertEdit {
+		return 0
+	}
+	return e.N
+}
+func editLenB(e *Edit) int {
+	if e.Type == DeleteEdit {
+		return 0
+	}
+	return e.N
+}
+
+//----------
+
+// Returns false if the edit distance is bigger than MaxEditDistance.
+func myers(a, b []string, oa, ob int, add func(EditType, int, int, int)) bool {
+	n, m := len(a), len(b)
+	max := n + m
+	if max == 0 {
+		return true
+	}
+	if max > MaxEditDistance {
+		max = MaxEditDistance
+	}
+	off := max + 1
+	v := make([]int, 2*max+3)
+
+	// v values at the start of each d (only the range used by the backtrack)
+	trace := [][]int{}
+
+	dend := -1
+	for d := 0; d <= max && dend < 0; d++ {
+		trace = append(trace, append([]int{}, v[off-d-1:off+d+2]...))
+		for k := -d; k <= d; k += 2 {
+			x := 0
+			if k == -d || (k != d && v[off+k-1] < v[off+k+1]) {
+				x = v[off+k+1] // down (insert)
+			} else {
+				x = v[off+k-1] + 1 // right (delete)
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[off+k] = x
+			if x >= n && y >= m {
+				dend = d
+				break
+			}
+		}
+	}
+	if dend < 0 {
+		return false
+	}
+
+	// backtrack
+	type op struct {
+		t    EditType
+		x, y int
+	}
+	rev := []op{}
+	x, y := n, m
+	for d := dend; d > 0; d-- {
+		tv := trace[d]
+		vk := func(k int) int { return tv[k+d+1] }
+		k := x - y
+		pk := k - 1
+		if k == -d || (k != d && vk(k-1) < vk(k+1)) {
+			pk = k + 1
+		}
+		px := vk(pk)
+		py := px - pk
+		for x > px && y > py {
+			x, y = x-1, y-1
+			rev = append(rev, op{EqualEdit, x, y})
+		}
+		if x == px {
+			y--
+			rev = append(rev, op{InsertEdit, x, y})
+		} else {
+			x--
+			rev = append(rev, op{DeleteEdit, x, y})
+		}
+	}
+	for x > 0 && y > 0 {
+		x, y = x-1, y-1
+		rev = append(rev, op{EqualEdit, x, y})
+	}
+
+	for i := len(rev) - 1; i >= 0; i-- {
+		o := rev[i]
+		add(o.t, oa+o.x, ob+o.y, 1)
+	}
+	return true
+}
+
+//----------
+
+// Splits keeping the newlines.
+func SplitLines(b []byte) []string {
+	u := []string{}
+	for len(b) > 0 {
+		i := bytes.IndexByte(b, '\n')
+		if i < 0 {
+			u = append(u, string(b))
+			break
+		}
+		u = append(u, string(b[:i+1]))
+		b = b[i+1:]
+	}
+	return u
+}
+
+//----------
+
+// Unified diff format with n lines of context. Returns empty if there are no differences.
+func Unified(nameA, nameB string, a, b []byte, n int) []byte {
+	la, lb := SplitLines(a), SplitLines(b)
+	edits := Diff(la, lb)
+
+	buf := &bytes.Buffer{}
+	writeLine := func(prefix, s string) {
+		buf.WriteString(prefix)
+		buf.WriteString(s)
+		if len(s) == 0 || s[len(s)-1] != '\n' {
+			buf.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+
+	for i := 0; i < len(edits); {
+		if edits[i].Type == EqualEdit {
+			i++
+			continue
+		}
+
+		// hunk edits: changes separated by less then 2*n equal lines
+		j := i + 1
+		for j < len(edits) {
+			e := edits[j]
+			if e.Type == EqualEdit && (e.N > 2*n || j == len(edits)-1) {
+				break
+			}
+			j++
+		}
+
+		// hunk range
+		as, bs := edits[i].A, edits[i].B
+		ctx1 := 0
+		if i > 0 {
+			ctx1 = mathutil.Smallest(n, edits[i-1].N)
+		}
+		as, bs = as-ctx1, bs-ctx1
+		ae, be := as, bs
+		if j > 0 {
+			e := edits[j-1]
+			ae, be = e.A+editLenA(e), e.B+editLenB(e)
+		}
+		ctx2 := 0
+		if j < len(edits) {
+			ctx2 = mathutil.Smallest(n, edits[j].N)
+		}
+		ae, be = ae+ctx2, be+ctx2
+
+		if buf.Len() == 0 {
+			fmt.Fprintf(buf, "--- %s\n+++ %s\n", nameA, nameB)
+		}
+		fmt.Fprintf(buf, "@@ -%s +%s @@\n", hunkRange(as, ae), hunkRange(bs, be))
+		for k := as; k < as+ctx1; k++ {
+			writeLine(" ", la[k])
+		}
+		for _, e := range edits[i:j] {
+			switch e.Type {
+			case EqualEdit:
+				for k := e.A; k < e.A+e.N; k++ {
+					writeLine(" ", la[k])
+				}
+			case DeleteEdit:
+				for k := e.A; k < e.A+e.N; k++ {
+					writeLine("-", la[k])
+				}
+			case InsertEdit:
+				for k := e.B; k < e.B+e.N; k++ {
+					writeLine("+", lb[k])
+				}
+			}
+		}
+		if j < len(edits) {
+			e := edits[j]
+			for k := e.A; k < e.A+ctx2; k++ {
+				writeLine(" ", la[k])
+			}
+		}
+		i = j
+	}
+	return buf.Bytes()
+}
+
+func hunkRange(s, e int) string {
+	n := e - s
+	if n == 0 {
+		return fmt.Sprintf("%d,0", s)
+	}
+	if n == 1 {
+		return fmt.Sprintf("%d", s+1)
+	}
+	return fmt.Sprintf("%d,%d", s+1, n)
+}
@@ -0,0 +1,75 @@
+package diffutil
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestDiff1(t *testing.T) {
+	a := SplitLines([]byte("a\nb\nc\nd\ne\n"))
+	b := SplitLines([]byte("a\nc\nd\nx\ne\n"))
+	edits := Diff(a, b)
+	if s := applyEdits(a, b, edits); s != strings.Join(b, "") {
+		t.Fatal(s)
+	}
+	if len(edits) != 5 {
+		t.Fatalf("%v", len(edits))
+	}
+}
+
+func TestDiff2(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	words := []string{"a\n", "b\n", "c\n", "d\n"}
+	gen := func() []string {
+		u := []string{}
+		for i := r.Intn(20); i > 0; i-- {
+			u = append(u, words[r.Intn(len(words))])
+		}
+		return u
+	}
+	for i := 0; i < 500; i++ {
+		a, b := gen(), gen()
+		if s := applyEdits(a, b, Diff(a, b)); s != strings.Join(b, "") {
+			t.Fatalf("%q %q: %q", a, b, s)
+		}
+	}
+}
+
+func TestUnified1(t *testing.T) {
+	a := "1\n2\n3\n4\n5\n6\n7\n8\n9\n"
+	b := "1\n2\n3\n4\nx\n6\n7\n8\n9"
+	s := string(Unified("a", "b", []byte(a), []byte(b), 1))
+	exp := `--- a
++++ b
+@@ -4,3 +4,3 @@
+ 4
+-5
++x
+ 6
+@@ -8,2 +8,2 @@
+ 8
+-9
++9
+\ No newline at end of file
+`
+	if s != exp {
+		t.Fatalf("\n%s", s)
+	}
+	if len(Unified("a", "b", []byte(a), []byte(a), 3)) != 0 {
+		t.Fatal("expecting no diff")
+	}
+}
+
+func applyEdits(a, b []string, edits []*Edit) string {
+	sb := &strings.Builder{}
+	for _, e := range edits {
+		switch e.Type {
+		case EqualEdit:
+			sb.WriteString(strings.Join(a[e.A:e.A+e.N], ""))
+		case InsertEdit:
+			sb.WriteString(strings.Join(b[e.B:e.B+e.N], ""))
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,148 @@
+package diffutil
+
+import (
+	"bytes"
+)
+
+// Base lines [Start,End) replaced by Lines.
+type hunk struct {
+	Start, End int
+	Lines      []string
+}
+
+// Groups the consecutive non-equal edits (base to other).
+func hunks(base, other []string) []*hunk {
+	u := []*hunk{}
+	var h *hunk
+	for _, e := range Diff(base, other) {
+		if e.Type == EqualEdit {
+			h = nil
+			continue
+		}
+		if h == nil {
+			h = &hunk{Start: e.A, End: e.A}
+			u = append(u, h)
+		}
+		switch e.Type {
+		case DeleteEdit:
+			h.End = e.A + e.N
+		case InsertEdit:
+			h.Lines = append(h.Lines, other[e.B:e.B+e.N]...)
+		}
+	}
+	return u
+}
+
+// Base lines [start,end) with the hunks applied (hunks must be inside the range).
+func applyHunks(base []string, start, end int, hs []*hunk) []string {
+	u := []string{}
+	k := start
+	for _, h := range hs {
+		u = append(u, base[k:h.Start]...)
+		u = append(u, h.Lines...)
+		k = h.End
+	}
+	return append(u, base[k:end]...)
+}
+
+//----------
+
+// Three-way merge of the changes from base to a, and from base to b. Overlapping (or adjacent) changes that differ are conflicts, written with markers using the names. Returns the merged lines and the number of conflicts.
+func Merge3(base, a, b []string, nameA, nameB string) ([]string, int) {
+	ha, hb := hunks(base, a), hunks(base, b)
+
+	res := []string{}
+	conflicts := 0
+	k := 0 // base index
+	for len(ha) > 0 || len(hb) > 0 {
+		// group of overlapping hunks from both sides
+		start, end := -1, -1
+		ga, gb := []*hunk{}, []*hunk{}
+		for {
+			if len(ha) > 0 && (start < 0 || ha[0].Start <= end) &&
+				(len(hb) == 0 || start >= 0 || ha[0].Start <= hb[0].Start) {
+				h := ha[0]
+				ha = ha[1:]
+				ga = append(ga, h)
+				start, end = groupRange(start, end, h)
+				continue
+			}
+			if len(hb) > 0 && (start < 0 || hb[0].Start <= end) {
+				h := hb[0]
+				hb = hb[1:]
+				gb = append(gb, h)
+				start, end = groupRange(start, end, h)
+				continue
+			}
+			break
+		}
+
+		res = append(res, base[k:start]...)
+		k = end
+
+		switch {
+		case len(gb) == 0:
+			res = append(res, applyHunks(base, start, end, ga)...)
+		case len(ga) == 0:
+			res = append(res, applyHunks(base, start, end, gb)...)
+		default:
+			la := applyHunks(base, start, end, ga)
+			lb := applyHunks(base, start, end, gb)
+			if equalLines(la, lb) {
+				res = append(res, la...)
+				break
+			}
+			conflicts++
+			res = append(res, "<<<<<<< "+nameA+"\n")
+			res = appendLinesNL(res, la)
+			res = append(res, "=======\n")
+			res = appendLinesNL(res, lb)
+			res = append(res, ">>>>>>> "+nameB+"\n")
+		}
+	}
+	res = append(res, base[k:]...)
+	return res, conflicts
+}
+
+func groupRange(start, end int, h *hunk) (int, int) {
+	if start < 0 {
+		return h.Start, h.End
+	}
+	if h.End > end {
+		end = h.End
+	}
+	return start, end
+}
+
+func equalLines(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Ensures the markers that follow start on a new line.
+func appendLinesNL(u []string, lines []string) []string {
+	u = append(u, lines...)
+	if n := len(u); len(lines) > 0 && u[n-1][len(u[n-1])-1] != '\n' {
+		u[n-1] += "\n"
+	}
+	return u
+}
+
+//----------
+
+// Merge3 with bytes content.
+func Merge3Bytes(base, a, b []byte, nameA, nameB string) ([]byte, int) {
+	lines, conflicts := Merge3(SplitLines(base), SplitLines(a), SplitLines(b), nameA, nameB)
+	buf := &bytes.Buffer{}
+	for _, l := range lines {
+		buf.WriteString(l)
+	}
+	return buf.Bytes(), conflicts
+}
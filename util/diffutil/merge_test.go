@@ -0,0 +1,33 @@
+package diffutil
+
+import (
+	"testing"
+)
+
+func TestMerge3(t *testing.T) {
+	type test struct {
+		base, a, b string
+		out        string
+		conflicts  int
+	}
+	tests := []test{
+		// changes in different places
+		{"1\n2\n3\n4\n5\n", "1\nx\n3\n4\n5\n", "1\n2\n3\n4\ny\n", "1\nx\n3\n4\ny\n", 0},
+		// same change on both sides
+		{"1\n2\n3\n", "1\nx\n3\n", "1\nx\n3\n", "1\nx\n3\n", 0},
+		// insert and delete
+		{"1\n2\n3\n4\n", "0\n1\n2\n3\n4\n", "1\n2\n4\n", "0\n1\n2\n4\n", 0},
+		// conflict
+		{"1\n2\n3\n", "1\nx\n3\n", "1\ny\n3\n", "1\n<<<<<<< a\nx\n=======\ny\n>>>>>>> b\n3\n", 1},
+		// conflict without newline at the end
+		{"1\n2", "1\nx", "1\ny", "1\n<<<<<<< a\nx\n=======\ny\n>>>>>>> b\n", 1},
+		// only one side changed
+		{"1\n2\n", "1\n2\n", "", "", 0},
+	}
+	for i, tt := range tests {
+		b, n := Merge3Bytes([]byte(tt.base), []byte(tt.a), []byte(tt.b), "a", "b")
+		if string(b) != tt.out || n != tt.conflicts {
+			t.Fatalf("%v: %v %q", i, n, b)
+		}
+	}
+}
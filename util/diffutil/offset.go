@@ -0,0 +1,70 @@
+package diffutil
+
+// Maps byte offsets of a content to the offsets of its new version (ex: keep the cursor position on reload).
+type OffsetMapper struct {
+	edits      []*Edit
+	startA     []int // line start offsets of a
+	startB     []int // line start offsets of b
+	lenA, lenB int
+	linesA     int
+}
+
+func NewOffsetMapper(a, b []byte) *OffsetMapper {
+	la, lb := SplitLines(a), SplitLines(b)
+	return &OffsetMapper{
+		edits:  Diff(la, lb),
+		startA: linesStarts(la),
+		startB: linesStarts(lb),
+		lenA:   len(a),
+		lenB:   len(b),
+		linesA: len(la),
+	}
+}
+
+// Offsets in unchanged lines keep the column. Offsets in changed lines map to the start of the replacement.
+func (m *OffsetMapper) Map(offset int) int {
+	if offset >= m.lenA {
+		return m.lenB
+	}
+	if offset < 0 {
+		return 0
+	}
+
+	// line of the offset
+	li := 0
+	for li+1 < m.linesA && m.startA[li+1] <= offset {
+		li++
+	}
+	col := offset - m.startA[li]
+
+	for _, e := range m.edits {
+		switch e.Type {
+		case EqualEdit:
+			if li >= e.A && li < e.A+e.N {
+				return m.startB[e.B+li-e.A] + col
+			}
+		case DeleteEdit:
+			if li >= e.A && li < e.A+e.N {
+				return m.lineStartB(e.B)
+			}
+		}
+	}
+	return m.lenB
+}
+
+func (m *OffsetMapper) lineStartB(i int) int {
+	if i < len(m.startB) {
+		return m.startB[i]
+	}
+	return m.lenB
+}
+
+func linesStarts(lines []string) []int {
+	u := make([]int, len(lines))
+	k := 0
+	for i, l := range lines {
+		u[i] = k
+		k += len(l)
+	}
+	return u
+}
@@ -0,0 +1,25 @@
+package diffutil
+
+import (
+	"testing"
+)
+
+func TestOffsetMapper(t *testing.T) {
+	a := []byte("aa\nbb\ncc\ndd\n")
+	b := []byte("xx\naa\ncc\nyy\ndd\n")
+	m := NewOffsetMapper(a, b)
+	type test struct{ in, out int }
+	tests := []test{
+		{0, 3},   // "aa" moved down
+		{1, 4},   // column kept
+		{4, 6},   // "bb" deleted: start of the replacement ("cc")
+		{7, 7},   // "cc" column kept
+		{9, 12},  // "dd"
+		{12, 15}, // end
+	}
+	for _, tt := range tests {
+		if v := m.Map(tt.in); v != tt.out {
+			t.Fatalf("%v: %v, expecting %v", tt.in, v, tt.out)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+// Text encoding and line ending detection, with conversion to/from utf8 and "\n" line endings.
+package encodingutil
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+type Encoding int
+
+const (
+	UTF8 Encoding = iota
+	UTF8BOM
+	UTF16LE // with bom
+	UTF16BE // with bom
+	Latin1
+)
+
+var encodingNames = []string{"utf8", "utf8bom", "utf16le", "utf16be", "latin1"}
+
+func (e Encoding) String() string {
+	if int(e) < len(encodingNames) {
+		return encodingNames[e]
+	}
+	return "?"
+}
+
+func ParseEncoding(s string) (Encoding, error) {
+	for i, n := range encodingNames {
+		if strings.ToLower(s) == n {
+			return Encoding(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown encoding: %q (expecting one of %v)", s, strings.Join(encodingNames, ", "))
+}
+
+//----------
+
+type LineEnding int
+
+const (
+	LF LineEnding = iota
+	CRLF
+)
+
+var lineEndingNames = []string{"lf", "crlf"}
+
+func (le LineEnding) String() string {
+	if int(le) < len(lineEndingNames) {
+		return lineEndingNames[le]
+	}
+	return "?"
+}
+
+func ParseLineEnding(s string) (LineEnding, error) {
+	for i, n := range lineEndingNames {
+		if strings.ToLower(s) == n {
+			return LineEnding(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown line ending: %q (expecting one of %v)", s, strings.Join(lineEndingNames, ", "))
+}
+
+//----------
+
+type Format struct {
+	Encoding   Encoding
+	LineEnding LineEnding
+}
+
+func (f Format) IsDefault() bool {
+	return f == Format{}
+}
+
+func (f Format) String() string {
+	return fmt.Sprintf("%v,%v", f.Encoding, f.LineEnding)
+}
+
+//----------
+
+var (
+	bomUTF8    = []byte{0xef, 0xbb, 0xbf}
+	bomUTF16LE = []byte{0xff, 0xfe}
+	bomUTF16BE = []byte{0xfe, 0xff}
+)
+
+var ErrUnknownEncoding = errors.New("unknown encoding, content kept as is")
+
+// Detects the format and returns the content as utf8 with "\n" line endings. Content that can't be decoded without loss (ex: binary) is returned as is with the default format. The error is only a warning (content still returned as is) for content that doesn't look binary but is in an unknown encoding.
+func Decode(b []byte) ([]byte, Format, error) {
+	f := Format{}
+	u, ok := decodeEncoding(b, &f.Encoding)
+	if !ok {
+		var err error
+		if !isBinary(b) {
+			err = ErrUnknownEncoding
+		}
+		return b, Format{}, err
+	}
+	// only if all the newlines are "\r\n", such that encoding restores the original content
+	if n := bytes.Count(u, []byte("\n")); n > 0 && n == bytes.Count(u, []byte("\r\n")) {
+		f.LineEnding = CRLF
+		u = bytes.Replace(u, []byte("\r\n"), []byte("\n"), -1)
+	}
+	return u, f, nil
+}
+
+func decodeEncoding(b []byte, enc *Encoding) ([]byte, bool) {
+	switch {
+	case bytes.HasPrefix(b, bomUTF8):
+		*enc = UTF8BOM
+		return b[len(bomUTF8):], true
+	case bytes.HasPrefix(b, bomUTF16LE):
+		*enc = UTF16LE
+		return decodeUTF16(b[len(bomUTF16LE):], binary.LittleEndian)
+	case bytes.HasPrefix(b, bomUTF16BE):
+		*enc = UTF16BE
+		return decodeUTF16(b[len(bomUTF16BE):], binary.BigEndian)
+	case utf8.Valid(b):
+		*enc = UTF8
+		return b, true
+	case isBinary(b):
+		return nil, false
+	case isLatin1(b):
+		*enc = Latin1
+		return decodeLatin1(b), true
+	default:
+		return nil, false // unknown (ex: windows-1252, other 8-bit encodings)
+	}
+}
+
+func isBinary(b []byte) bool {
+	return bytes.IndexByte(b, 0) >= 0
+}
+
+// Latin1 text is not expected to have C1 control chars (0x80-0x9f), which are used for printable chars by other 8-bit encodings (ex: windows-1252 quotes).
+func isLatin1(b []byte) bool {
+	for _, c := range b {
+		if c >= 0x80 && c <= 0x9f {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns the content in the given format, converting from utf8 with "\n" line endings.
+func Encode(b []byte, f Format) ([]byte, error) {
+	if f.LineEnding == CRLF {
+		b = bytes.Replace(b, []byte("\n"), []byte("\r\n"), -1)
+	}
+	switch f.Encoding {
+	case UTF8:
+		return b, nil
+	case UTF8BOM:
+		return append(append([]byte{}, bomUTF8...), b...), nil
+	case UTF16LE:
+		return encodeUTF16(b, bomUTF16LE, binary.LittleEndian), nil
+	case UTF16BE:
+		return encodeUTF16(b, bomUTF16BE, binary.BigEndian), nil
+	case Latin1:
+		return encodeLatin1(b)
+	}
+	return nil, fmt.Errorf("unexpected encoding: %v", f.Encoding)
+}
+
+//----------
+
+func decodeUTF16(b []byte, order binary.ByteOrder) ([]byte, bool) {
+	if len(b)%2 != 0 {
+		return nil, false
+	}
+	u := make([]uint16, len(b)/2)
+	for i := range u {
+		u[i] = order.Uint16(b[i*2:])
+	}
+	// not using utf16.Decode: unpaired surrogates are replaced with U+FFFD, and would be lost on save
+	buf := &bytes.Buffer{}
+	for i := 0; i < len(u); i++ {
+		ru := rune(u[i])
+		if utf16.IsSurrogate(ru) {
+			if i+1 >= len(u) {
+				return nil, false
+			}
+			ru = utf16.DecodeRune(ru, rune(u[i+1]))
+			if ru == unicode.ReplacementChar {
+				return nil, false
+			}
+			i++
+		}
+		buf.WriteRune(ru)
+	}
+	return buf.Bytes(), true
+}
+
+func encodeUTF16(b []byte, bom []byte, order binary.ByteOrder) []byte {
+	u := utf16.Encode([]rune(string(b)))
+	w := make([]byte, len(bom)+len(u)*2)
+	copy(w, bom)
+	for i, v := range u {
+		order.PutUint16(w[len(bom)+i*2:], v)
+	}
+	return w
+}
+
+func decodeLatin1(b []byte) []byte {
+	buf := &bytes.Buffer{}
+	for _, c := range b {
+		buf.WriteRune(rune(c))
+	}
+	return buf.Bytes()
+}
+
+func encodeLatin1(b []byte) ([]byte, error) {
+	w := make([]byte, 0, len(b))
+	for i := 0; i < len(b); {
+		ru, size := utf8.DecodeRune(b[i:])
+		if ru > 0xff || (ru == utf8.RuneError && size == 1) {
+			return nil, fmt.Errorf("latin1: unable to encode %q at index %v", b[i:i+size], i)
+		}
+		w = append(w, byte(ru))
+		i += size
+	}
+	return w, nil
+}
@@ -0,0 +1,79 @@
+package encodingutil
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDecodeEncode(t *testing.T) {
+	type test struct {
+		in  []byte
+		out string
+		f   Format
+	}
+	tests := []test{
+		{[]byte("a\nb\n"), "a\nb\n", Format{UTF8, LF}},
+		{[]byte("a\r\nb\r\n"), "a\nb\n", Format{UTF8, CRLF}},
+		{[]byte("a\r\nb\n"), "a\r\nb\n", Format{UTF8, LF}}, // mixed
+		{[]byte("\xef\xbb\xbfa\n"), "a\n", Format{UTF8BOM, LF}},
+		{[]byte("\xff\xfea\x00\r\x00\n\x00"), "a\n", Format{UTF16LE, CRLF}},
+		{[]byte("\xfe\xff\x00a\x00\xe9"), "aé", Format{UTF16BE, LF}},
+		{[]byte("caf\xe9\n"), "café\n", Format{Latin1, LF}},
+		{[]byte("a\x00\xff"), "a\x00\xff", Format{UTF8, LF}},                         // binary
+		{[]byte("\xff\xfe\x3d\xd8\x00\xde"), "\U0001F600", Format{UTF16LE, LF}},      // surrogate pair
+		{[]byte("\xff\xfe\x3d\xd8a\x00"), "\xff\xfe\x3d\xd8a\x00", Format{UTF8, LF}}, // unpaired surrogate (binary)
+	}
+	for i, tt := range tests {
+		u, f, err := Decode(tt.in)
+		if err != nil {
+			t.Fatal(i, err)
+		}
+		if string(u) != tt.out || f != tt.f {
+			t.Fatalf("%v: %q %v", i, u, f)
+		}
+		b, err := Encode(u, f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(b, tt.in) {
+			t.Fatalf("%v: %q", i, b)
+		}
+	}
+}
+
+func TestDecodeUnknown(t *testing.T) {
+	// windows-1252 quotes: not latin1, kept as is
+	in := []byte("\x93a\x94 caf\xe9\n")
+	u, f, err := Decode(in)
+	if err != ErrUnknownEncoding {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(u, in) || f != (Format{}) {
+		t.Fatalf("%q %v", u, f)
+	}
+	b, err := Encode(u, f)
+	if err != nil || !bytes.Equal(b, in) {
+		t.Fatalf("%q %v", b, err)
+	}
+}
+
+func TestEncodeLatin1Err(t *testing.T) {
+	_, err := Encode([]byte("a€"), Format{Encoding: Latin1})
+	if err == nil {
+		t.Fatal("expecting error")
+	}
+}
+
+func TestParse(t *testing.T) {
+	e, err := ParseEncoding("UTF16LE")
+	if err != nil || e != UTF16LE {
+		t.Fatal(e, err)
+	}
+	if _, err := ParseEncoding("utf32"); err == nil {
+		t.Fatal("expecting error")
+	}
+	le, err := ParseLineEnding("crlf")
+	if err != nil || le != CRLF {
+		t.Fatal(le, err)
+	}
+}
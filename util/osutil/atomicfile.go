@@ -0,0 +1,174 @@
+package osutil
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// Writes to a temporary file in the same directory, and renames it over the target, such that a failure while writing doesn't leave a truncated file. Symlinks are followed (the link is kept). The mode, ownership and extended attributes of an existing file are kept. If backup is true, the previous content is kept in "<filename>~".
+// Falls back to writing in place if the rename is not possible (ex: no permission to create files in the directory, hard links that would be broken, ownership that can't be kept).
+func WriteFileAtomic(filename string, b []byte, perm os.FileMode, backup bool) error {
+	target, err := ResolveSymlinks(filename)
+	if err != nil {
+		return err
+	}
+
+	fi, err := os.Stat(target)
+	exists := err == nil
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if exists {
+		perm = fi.Mode() & (os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky)
+	}
+
+	if exists && backup {
+		if err := copyFile(target, target+"~", perm); err != nil {
+			return err
+		}
+	}
+
+	if exists && fileHardLinks(fi) > 1 {
+		return writeFileInPlace(target, b, perm)
+	}
+
+	ok, err := writeFileRename(target, b, perm, fi)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return writeFileInPlace(target, b, perm)
+	}
+	return nil
+}
+
+// Returns false (and no error) if the fallback should be used.
+func writeFileRename(target string, b []byte, perm os.FileMode, fi os.FileInfo) (bool, error) {
+	dir, base := filepath.Split(target)
+	f, err := createTempFile(dir, "."+base+".tmp", perm)
+	if err != nil {
+		return false, nil
+	}
+	tmp := f.Name()
+	done := false
+	defer func() {
+		if !done {
+			_ = os.Remove(tmp)
+		}
+	}()
+
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return false, err // ex: disk full, the original file is untouched
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return false, err
+	}
+	// chown before chmod: chown clears the setuid/setgid bits
+	if fi != nil {
+		if err := copyFileOwner(f, fi); err != nil {
+			f.Close()
+			return false, nil // write in place to keep the ownership
+		}
+	}
+	// a new file keeps the mode it was created with (umask applied)
+	if fi != nil {
+		if err := f.Chmod(perm); err != nil {
+			f.Close()
+			return false, err
+		}
+		copyFileXattrs(f, target) // best effort
+	}
+	if err := f.Close(); err != nil {
+		return false, err
+	}
+
+	if err := os.Rename(tmp, target); err != nil {
+		return false, nil
+	}
+	done = true
+	syncDir(dir)
+	return true, nil
+}
+
+// Like ioutil.TempFile, but the file is created with perm (umask applied) instead of 0600.
+func createTempFile(dir, prefix string, perm os.FileMode) (*os.File, error) {
+	for i := 0; i < 1000; i++ {
+		name := filepath.Join(dir, prefix+strconv.FormatUint(uint64(rand.Uint32()), 10))
+		f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, perm)
+		if os.IsExist(err) {
+			continue
+		}
+		return f, err
+	}
+	return nil, fmt.Errorf("unable to create temporary file in dir: %v", dir)
+}
+
+func writeFileInPlace(filename string, b []byte, perm os.FileMode) error {
+	flags := os.O_WRONLY | os.O_TRUNC | os.O_CREATE
+	f, err := os.OpenFile(filename, flags, perm)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := f.Write(b); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+func copyFile(src, dst string, perm os.FileMode) error {
+	b, err := ioutil.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	return writeFileInPlace(dst, b, perm)
+}
+
+// Makes the rename durable (errors ignored, not supported on some systems).
+func syncDir(dir string) {
+	if dir == "" {
+		dir = "."
+	}
+	f, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	_ = f.Sync()
+	_ = f.Close()
+}
+
+//----------
+
+var errTooManyLinks = errors.New("too many links")
+
+// Follows the symlinks, including a last one that points to a file that doesn't exist yet.
+func ResolveSymlinks(filename string) (string, error) {
+	for i := 0; i < 255; i++ {
+		fi, err := os.Lstat(filename)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return filename, nil
+			}
+			return "", err
+		}
+		if fi.Mode()&os.ModeSymlink == 0 {
+			return filename, nil
+		}
+		link, err := os.Readlink(filename)
+		if err != nil {
+			return "", err
+		}
+		if !filepath.IsAbs(link) {
+			link = filepath.Join(filepath.Dir(filename), link)
+		}
+		filename = link
+	}
+	return "", &os.PathError{Op: "resolve symlinks", Path: filename, Err: errTooManyLinks}
+}
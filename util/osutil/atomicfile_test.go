@@ -0,0 +1,167 @@
+// +build !windows
+
+package osutil
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"syscall"
+	"testing"
+)
+
+func TestWriteFileAtomic1(t *testing.T) {
+	dir, err := ioutil.TempDir("", "atomicfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fn := filepath.Join(dir, "a.txt")
+	if err := ioutil.WriteFile(fn, []byte("aaa"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "link.txt")
+	if err := os.Symlink("a.txt", link); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := WriteFileAtomic(link, []byte("bbb"), 0644, true); err != nil {
+		t.Fatal(err)
+	}
+
+	// link kept
+	fi, err := os.Lstat(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode()&os.ModeSymlink == 0 {
+		t.Fatal("symlink replaced")
+	}
+	// content and mode
+	testFileContent(t, fn, "bbb")
+	fi, err = os.Stat(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0600 {
+		t.Fatal(fi.Mode())
+	}
+	// backup
+	testFileContent(t, fn+"~", "aaa")
+
+	// no tmp files left
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fis) != 3 {
+		t.Fatal(len(fis))
+	}
+}
+
+func TestWriteFileAtomic2(t *testing.T) {
+	dir, err := ioutil.TempDir("", "atomicfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// hard link: written in place to keep the link
+	fn := filepath.Join(dir, "a.txt")
+	if err := ioutil.WriteFile(fn, []byte("aaa"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	fn2 := filepath.Join(dir, "b.txt")
+	if err := os.Link(fn, fn2); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFileAtomic(fn, []byte("bbb"), 0644, false); err != nil {
+		t.Fatal(err)
+	}
+	testFileContent(t, fn2, "bbb")
+
+	// new file
+	fn3 := filepath.Join(dir, "c.txt")
+	if err := WriteFileAtomic(fn3, []byte("ccc"), 0640, true); err != nil {
+		t.Fatal(err)
+	}
+	testFileContent(t, fn3, "ccc")
+	if _, err := os.Stat(fn3 + "~"); !os.IsNotExist(err) {
+		t.Fatal("unexpected backup")
+	}
+}
+
+func TestWriteFileAtomic3(t *testing.T) {
+	if runtime.GOOS != "linux" || os.Getuid() != 0 {
+		t.Skip("needs root to change the file owner")
+	}
+	dir, err := ioutil.TempDir("", "atomicfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// ownership and setgid kept
+	fn := filepath.Join(dir, "a.txt")
+	if err := ioutil.WriteFile(fn, []byte("aaa"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chown(fn, 65534, 65534); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(fn, 0755|os.ModeSetgid); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFileAtomic(fn, []byte("bbb"), 0644, false); err != nil {
+		t.Fatal(err)
+	}
+	testFileContent(t, fn, "bbb")
+	fi, err := os.Stat(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := fi.Sys().(*syscall.Stat_t)
+	if st.Uid != 65534 || st.Gid != 65534 {
+		t.Fatal(st.Uid, st.Gid)
+	}
+	if fi.Mode()&os.ModeSetgid == 0 || fi.Mode().Perm() != 0755 {
+		t.Fatal(fi.Mode())
+	}
+}
+
+func TestWriteFileAtomic4(t *testing.T) {
+	dir, err := ioutil.TempDir("", "atomicfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// new file: umask applied
+	old := syscall.Umask(077)
+	defer syscall.Umask(old)
+	fn := filepath.Join(dir, "a.txt")
+	if err := WriteFileAtomic(fn, []byte("aaa"), 0644, false); err != nil {
+		t.Fatal(err)
+	}
+	testFileContent(t, fn, "aaa")
+	fi, err := os.Stat(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0600 {
+		t.Fatal(fi.Mode())
+	}
+}
+
+func testFileContent(t *testing.T, filename, s string) {
+	t.Helper()
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != s {
+		t.Fatalf("%v: %q", filename, b)
+	}
+}
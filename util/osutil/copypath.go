@@ -0,0 +1,63 @@
+package osutil
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Copies the file, or the directory recursively. Fails if dst exists, or is inside src (would copy into itself). Symlinks are copied as links.
+func CopyPath(src, dst string) error {
+	if _, err := os.Lstat(dst); err == nil {
+		return &os.PathError{Op: "copy", Path: dst, Err: os.ErrExist}
+	}
+	src2, err := filepath.Abs(src)
+	if err != nil {
+		return err
+	}
+	dst2, err := filepath.Abs(dst)
+	if err != nil {
+		return err
+	}
+	if FilepathHasDirPrefix(dst2, src2) {
+		return fmt.Errorf("copy: destination is inside the source: %v", dst)
+	}
+	return copyPath(src, dst)
+}
+
+func copyPath(src, dst string) error {
+	fi, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+	switch {
+	case fi.Mode()&os.ModeSymlink != 0:
+		link, err := os.Readlink(src)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(link, dst)
+	case fi.IsDir():
+		if err := os.Mkdir(dst, fi.Mode().Perm()); err != nil {
+			return err
+		}
+		f, err := os.Open(src)
+		if err != nil {
+			return err
+		}
+		names, err := f.Readdirnames(-1)
+		f.Close()
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			err := copyPath(filepath.Join(src, name), filepath.Join(dst, name))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return copyFile(src, dst, fi.Mode().Perm())
+	}
+}
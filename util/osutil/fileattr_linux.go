@@ -0,0 +1,74 @@
+package osutil
+
+import (
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+func fileHardLinks(fi os.FileInfo) int {
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+		return int(st.Nlink)
+	}
+	return 1
+}
+
+func IsOwnedByUser(fi os.FileInfo) bool {
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+		return int(st.Uid) == os.Getuid()
+	}
+	return false
+}
+
+// Sets the ownership of the original file. Fails if not allowed (ex: file owned by another user), in which case the original file should be written in place to keep its ownership.
+func copyFileOwner(f *os.File, fi os.FileInfo) error {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+	return f.Chown(int(st.Uid), int(st.Gid))
+}
+
+// Copies the extended attributes from the original file (errors are ignored).
+func copyFileXattrs(f *os.File, orig string) {
+	sz, err := unix.Listxattr(orig, nil)
+	if err != nil || sz <= 0 {
+		return
+	}
+	buf := make([]byte, sz)
+	sz, err = unix.Listxattr(orig, buf)
+	if err != nil {
+		return
+	}
+	for _, name := range splitNullTerminated(buf[:sz]) {
+		vsz, err := unix.Getxattr(orig, name, nil)
+		if err != nil {
+			continue
+		}
+		v := make([]byte, vsz)
+		vsz, err = unix.Getxattr(orig, name, v)
+		if err != nil {
+			continue
+		}
+		_ = unix.Fsetxattr(int(f.Fd()), name, v[:vsz], 0)
+	}
+}
+
+func splitNullTerminated(b []byte) []string {
+	u := []string{}
+	for len(b) > 0 {
+		i := 0
+		for i < len(b) && b[i] != 0 {
+			i++
+		}
+		if i > 0 {
+			u = append(u, string(b[:i]))
+		}
+		if i < len(b) {
+			i++
+		}
+		b = b[i:]
+	}
+	return u
+}
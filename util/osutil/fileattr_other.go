@@ -0,0 +1,23 @@
+// +build !linux
+
+package osutil
+
+import (
+	"os"
+)
+
+func fileHardLinks(fi os.FileInfo) int {
+	return 1
+}
+
+// Unknown in this system (false).
+func IsOwnedByUser(fi os.FileInfo) bool {
+	return false
+}
+
+func copyFileOwner(f *os.File, fi os.FileInfo) error {
+	return nil
+}
+
+func copyFileXattrs(f *os.File, orig string) {
+}
@@ -23,14 +23,32 @@ func KillExecCmd(cmd *exec.Cmd) error {
 	return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
 }
 
+// Allows the processes to exit gracefully, use KillExecCmd if they don't.
+func TermExecCmd(cmd *exec.Cmd) error {
+	return syscall.Kill(-cmd.Process.Pid, syscall.SIGTERM)
+}
+
 //----------
 
 func ShellRunArgs(args ...string) []string {
 	return []string{"sh", "-c", strings.Join(args, " ")}
 }
 
+// Quotes the string to be used as one argument in the ShellRunArgs cmd line.
+func ShellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
 //----------
 
 func ExecName(name string) string {
 	return name
 }
+
+//----------
+
+func ProcessExists(pid int) bool {
+	// signal zero only checks for the existence of the process
+	err := syscall.Kill(pid, syscall.Signal(0))
+	return err == nil || err == syscall.EPERM
+}
@@ -3,7 +3,9 @@
 package osutil
 
 import (
+	"os"
 	"os/exec"
+	"strings"
 	"syscall"
 )
 
@@ -29,14 +31,36 @@ func KillExecCmd(cmd *exec.Cmd) error {
 	//return c.Run()
 }
 
+func TermExecCmd(cmd *exec.Cmd) error {
+	// no graceful termination signal
+	return KillExecCmd(cmd)
+}
+
 //----------
 
 func ShellRunArgs(args ...string) []string {
 	return append([]string{"cmd", "/C"}, args...)
 }
 
+// Quotes the string to be used as one argument in the ShellRunArgs cmd line.
+func ShellQuote(s string) string {
+	return `"` + strings.Replace(s, `"`, `""`, -1) + `"`
+}
+
 //----------
 
 func ExecName(name string) string {
 	return name + ".exe"
 }
+
+//----------
+
+func ProcessExists(pid int) bool {
+	// fails if the process doesn't exist
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	_ = p.Release()
+	return true
+}
@@ -0,0 +1,31 @@
+package osutil
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+// Identifies the process instance (boot id and start time), allowing to detect a reused pid (ex: after a reboot, or in a container).
+func ProcessStartId(pid int) (string, bool) {
+	b, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return "", false
+	}
+	// the cmd name (2nd field) can have spaces: use the fields after it
+	i := bytes.LastIndexByte(b, ')')
+	if i < 0 {
+		return "", false
+	}
+	f := strings.Fields(string(b[i+1:]))
+	if len(f) < 20 {
+		return "", false
+	}
+	start := f[19] // 22nd field
+	boot, err := ioutil.ReadFile("/proc/sys/kernel/random/boot_id")
+	if err != nil {
+		return "", false
+	}
+	return strings.TrimSpace(string(boot)) + ":" + start, true
+}
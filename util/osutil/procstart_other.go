@@ -0,0 +1,8 @@
+// +build !linux
+
+package osutil
+
+// Unknown in this system (false).
+func ProcessStartId(pid int) (string, bool) {
+	return "", false
+}
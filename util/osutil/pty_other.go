@@ -0,0 +1,23 @@
+// +build !linux
+
+package osutil
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+func OpenPty() (master, slave *os.File, _ error) {
+	return nil, nil, fmt.Errorf("pty: not supported on this platform")
+}
+
+func SetPtySize(f *os.File, cols, rows int) error {
+	return fmt.Errorf("pty: not supported on this platform")
+}
+
+func SetPtyNoEcho(f *os.File) error {
+	return fmt.Errorf("pty: not supported on this platform")
+}
+
+func SetupExecCmdPty(cmd *exec.Cmd) {}
@@ -0,0 +1,81 @@
+package osutil
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Moves the file or directory to the user trash (freedesktop.org trash spec: "$XDG_DATA_HOME/Trash"). Fails if the trash is in another filesystem.
+func MoveToTrash(name string) error {
+	abs, err := filepath.Abs(name)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Lstat(abs); err != nil {
+		return err
+	}
+
+	dir, err := trashDir()
+	if err != nil {
+		return err
+	}
+	filesDir := filepath.Join(dir, "files")
+	infoDir := filepath.Join(dir, "info")
+	if err := os.MkdirAll(filesDir, 0700); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(infoDir, 0700); err != nil {
+		return err
+	}
+
+	// reserve a name by creating the info file
+	base := filepath.Base(abs)
+	var info *os.File
+	var tname string
+	for i := 1; ; i++ {
+		tname = base
+		if i > 1 {
+			tname = fmt.Sprintf("%v.%v", base, i)
+		}
+		p := filepath.Join(infoDir, tname+".trashinfo")
+		f, err := os.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err == nil {
+			info = f
+			break
+		}
+		if !os.IsExist(err) {
+			return err
+		}
+	}
+	infoName := info.Name()
+
+	u := &url.URL{Path: abs}
+	date := time.Now().Format("2006-01-02T15:04:05")
+	s := fmt.Sprintf("[Trash Info]\nPath=%v\nDeletionDate=%v\n", u.EscapedPath(), date)
+	_, err = info.WriteString(s)
+	if err2 := info.Close(); err == nil {
+		err = err2
+	}
+	if err == nil {
+		err = os.Rename(abs, filepath.Join(filesDir, tname))
+	}
+	if err != nil {
+		_ = os.Remove(infoName)
+		return err
+	}
+	return nil
+}
+
+func trashDir() (string, error) {
+	if d := os.Getenv("XDG_DATA_HOME"); d != "" {
+		return filepath.Join(d, "Trash"), nil
+	}
+	h, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(h, ".local", "share", "Trash"), nil
+}
@@ -0,0 +1,96 @@
+// +build !windows
+
+package osutil
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMoveToTrash1(t *testing.T) {
+	dir, err := ioutil.TempDir("", "trash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	old := os.Getenv("XDG_DATA_HOME")
+	defer os.Setenv("XDG_DATA_HOME", old)
+	os.Setenv("XDG_DATA_HOME", filepath.Join(dir, "data"))
+
+	fn := filepath.Join(dir, "a b.txt")
+	for i := 0; i < 2; i++ {
+		if err := ioutil.WriteFile(fn, []byte("aaa"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := MoveToTrash(fn); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := os.Stat(fn); !os.IsNotExist(err) {
+		t.Fatal("file not removed")
+	}
+
+	trash := filepath.Join(dir, "data", "Trash")
+	for _, name := range []string{"a b.txt", "a b.txt.2"} {
+		b, err := ioutil.ReadFile(filepath.Join(trash, "files", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != "aaa" {
+			t.Fatal(string(b))
+		}
+		info, err := ioutil.ReadFile(filepath.Join(trash, "info", name+".trashinfo"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(info), "Path="+filepath.Dir(fn)+"/a%20b.txt\n") {
+			t.Fatal(string(info))
+		}
+	}
+}
+
+func TestCopyPath1(t *testing.T) {
+	dir, err := ioutil.TempDir("", "copypath")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "src")
+	if err := os.MkdirAll(filepath.Join(src, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	fn := filepath.Join(src, "sub", "a.txt")
+	if err := ioutil.WriteFile(fn, []byte("aaa"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := filepath.Join(dir, "dst")
+	if err := CopyPath(src, dst); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(filepath.Join(dst, "sub", "a.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "aaa" {
+		t.Fatal(string(b))
+	}
+
+	// dst exists
+	if err := CopyPath(src, dst); !os.IsExist(err) {
+		t.Fatal(err)
+	}
+
+	// dst inside src
+	if err := CopyPath(src, filepath.Join(src, "sub", "src")); err == nil {
+		t.Fatal("expecting error")
+	}
+	if _, err := os.Lstat(filepath.Join(src, "sub", "src")); !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+}
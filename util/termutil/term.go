@@ -0,0 +1,522 @@
+package termutil
+
+import (
+	"bytes"
+	"image/color"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/jmigpin/editor/util/imageutil"
+)
+
+// https://en.wikipedia.org/wiki/ANSI_escape_code
+// https://invisible-island.net/xterm/ctlseqs/ctlseqs.html
+
+//----------
+
+const maxPending = 4 * 1024
+
+// Line based terminal emulator. The output is kept as text that grows (no fixed screen). Only the last lines (the screen) can be rewritten with cursor movements. Lines that scroll off the screen are committed (can't be changed anymore).
+type Term struct {
+	cols, rows int
+
+	lines []tline // screen
+	cur   struct {
+		row, col int
+	}
+	style Style // current graphic rendition
+	sgr   sgrState
+
+	// parse state
+	pending []byte // incomplete utf8 or escape sequence
+	upd     *Update
+}
+
+func NewTerm(cols, rows int) *Term {
+	if cols <= 0 {
+		cols = 80
+	}
+	if rows <= 0 {
+		rows = 24
+	}
+	t := &Term{cols: cols, rows: rows}
+	t.lines = []tline{nil}
+	return t
+}
+
+//----------
+
+// Processes the output and returns the changes to apply to the text.
+func (t *Term) Write(p []byte) *Update {
+	t.upd = &Update{}
+	b := append(t.pending, p...)
+	t.pending = nil
+	for len(b) > 0 {
+		n, ok := t.parse(b)
+		if !ok {
+			if len(b) < maxPending {
+				// incomplete, wait for more data
+				t.pending = append([]byte{}, b...)
+				break
+			}
+			n = 1 // unterminated sequence, skip
+		}
+		b = b[n:]
+	}
+	t.commitOffscreen()
+	t.upd.Screen, t.upd.ScreenOps = t.render(t.lines)
+	return t.upd
+}
+
+//----------
+
+func (t *Term) parse(b []byte) (int, bool) {
+	switch b[0] {
+	case 27: // escape
+		return t.parseEscape(b)
+	case '\r':
+		t.cur.col = 0
+	case '\n':
+		t.newline()
+	case '\b':
+		if t.cur.col > 0 {
+			t.cur.col--
+		}
+	case 7, 14, 15: // bell, shift out/in
+		// ignored
+	default:
+		ru, size := utf8.DecodeRune(b)
+		if ru == utf8.RuneError && size <= 1 && !utf8.FullRune(b) {
+			return 0, false
+		}
+		t.put(ru)
+		return size, true
+	}
+	return 1, true
+}
+
+func (t *Term) parseEscape(b []byte) (int, bool) {
+	if len(b) < 2 {
+		return 0, false
+	}
+	switch b[1] {
+	case '[':
+		return t.parseCSI(b)
+	case ']':
+		return t.parseOSC(b)
+	case '(', ')': // charset
+		if len(b) < 3 {
+			return 0, false
+		}
+		return 3, true
+	case 'c': // reset
+		t.clear()
+		t.setSGR(nil)
+		return 2, true
+	default:
+		return 2, true // ignored
+	}
+}
+
+// Control Sequence Introducer
+func (t *Term) parseCSI(b []byte) (int, bool) {
+	for i := 2; i < len(b); i++ {
+		c := b[i]
+		switch {
+		case c >= 0x20 && c <= 0x3f: // param/intermediate bytes
+		case c >= 0x40 && c <= 0x7e: // final byte
+			t.interpretCSI(string(b[2:i]), c)
+			return i + 1, true
+		default:
+			// invalid, ignore the escape
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// Operating System Command (ex: window title). Ignored.
+func (t *Term) parseOSC(b []byte) (int, bool) {
+	for i := 2; i < len(b); i++ {
+		switch b[i] {
+		case 7: // bell
+			return i + 1, true
+		case 27: // string terminator "esc\"
+			if i+1 >= len(b) {
+				return 0, false
+			}
+			return i + 2, true
+		}
+	}
+	return 0, false
+}
+
+func (t *Term) interpretCSI(param string, final byte) {
+	if len(param) > 0 && (param[0] == '?' || param[0] == '>' || param[0] == '=') {
+		return // private modes: ignored
+	}
+	args := parseParams(param)
+	arg := func(i, def int) int {
+		if i < len(args) && args[i] > 0 {
+			return args[i]
+		}
+		return def
+	}
+	switch final {
+	case 'A': // cursor up
+		t.moveTo(t.cur.row-arg(0, 1), t.cur.col)
+	case 'B': // cursor down
+		t.moveTo(t.cur.row+arg(0, 1), t.cur.col)
+	case 'C': // cursor forward
+		t.moveTo(t.cur.row, t.cur.col+arg(0, 1))
+	case 'D': // cursor back
+		t.moveTo(t.cur.row, t.cur.col-arg(0, 1))
+	case 'E': // cursor next line
+		t.moveTo(t.cur.row+arg(0, 1), 0)
+	case 'F': // cursor previous line
+		t.moveTo(t.cur.row-arg(0, 1), 0)
+	case 'G': // cursor horizontal absolute
+		t.moveTo(t.cur.row, arg(0, 1)-1)
+	case 'H', 'f': // cursor position
+		t.moveTo(arg(0, 1)-1, arg(1, 1)-1)
+	case 'J': // erase in display
+		t.eraseInDisplay(arg(0, 0))
+	case 'K': // erase in line
+		t.eraseInLine(arg(0, 0))
+	case 'm': // select graphic rendition
+		t.setSGR(args)
+	}
+}
+
+//----------
+
+func (t *Term) put(ru rune) {
+	l := &t.lines[t.cur.row]
+	for len(*l) < t.cur.col {
+		*l = append(*l, tcell{ru: ' '})
+	}
+	c := tcell{ru: ru, style: t.style}
+	if t.cur.col < len(*l) {
+		(*l)[t.cur.col] = c
+	} else {
+		*l = append(*l, c)
+	}
+	t.cur.col++
+}
+
+func (t *Term) newline() {
+	t.cur.row++
+	t.cur.col = 0
+	t.ensureRow(t.cur.row)
+}
+
+func (t *Term) moveTo(row, col int) {
+	if row < 0 {
+		row = 0
+	}
+	if col < 0 {
+		col = 0
+	}
+	if col >= t.cols {
+		col = t.cols - 1
+	}
+	// rows are relative to the screen, which can be smaller then the number of rows
+	if row >= t.rows {
+		row = t.rows - 1
+	}
+	t.ensureRow(row)
+	t.cur.row, t.cur.col = row, col
+}
+
+func (t *Term) ensureRow(row int) {
+	for len(t.lines) <= row {
+		t.lines = append(t.lines, nil)
+	}
+	t.commitOffscreen()
+}
+
+func (t *Term) eraseInDisplay(mode int) {
+	switch mode {
+	case 0: // cursor to end of screen
+		t.eraseInLine(0)
+		t.lines = t.lines[:t.cur.row+1]
+	case 1: // start of screen to cursor
+		for i := 0; i < t.cur.row; i++ {
+			t.lines[i] = nil
+		}
+		t.eraseInLine(1)
+	case 2, 3: // all (also previous output)
+		t.clear()
+	}
+}
+
+func (t *Term) eraseInLine(mode int) {
+	l := &t.lines[t.cur.row]
+	switch mode {
+	case 0: // cursor to end of line
+		if t.cur.col < len(*l) {
+			*l = (*l)[:t.cur.col]
+		}
+	case 1: // start of line to cursor
+		for i := 0; i <= t.cur.col && i < len(*l); i++ {
+			(*l)[i] = tcell{ru: ' '}
+		}
+	case 2: // all line
+		*l = nil
+	}
+}
+
+func (t *Term) clear() {
+	t.lines = []tline{nil}
+	t.cur.row, t.cur.col = 0, 0
+	t.upd.Clear = true
+	t.upd.Commit, t.upd.CommitOps = nil, nil
+}
+
+// Lines that scrolled off the screen can't be changed anymore.
+func (t *Term) commitOffscreen() {
+	n := len(t.lines) - t.rows
+	if n <= 0 {
+		return
+	}
+	b, ops := t.render(t.lines[:n+1])
+	// render of n+1 lines to have the last newline, but not the next line content
+	k := bytes.LastIndexByte(b, '\n') + 1
+	b = b[:k]
+	for len(ops) > 0 && ops[len(ops)-1].Offset >= k {
+		ops = ops[:len(ops)-1]
+	}
+	if len(ops) > 0 && !ops[len(ops)-1].Style.IsZero() {
+		// ensure style ends with the commit
+		ops = append(ops, &Op{Offset: k})
+	}
+
+	o := len(t.upd.Commit)
+	for _, op := range ops {
+		op.Offset += o
+	}
+	t.upd.Commit = append(t.upd.Commit, b...)
+	t.upd.CommitOps = append(t.upd.CommitOps, ops...)
+
+	t.lines = t.lines[n:]
+	t.cur.row -= n
+}
+
+//----------
+
+func (t *Term) render(lines []tline) ([]byte, []*Op) {
+	buf := &bytes.Buffer{}
+	ops := []*Op{}
+	st := Style{}
+	var rb [utf8.UTFMax]byte
+	for i, l := range lines {
+		if i > 0 {
+			buf.WriteByte('\n')
+		}
+		for _, c := range l {
+			if c.style != st {
+				st = c.style
+				ops = append(ops, &Op{Offset: buf.Len(), Style: st})
+			}
+			n := utf8.EncodeRune(rb[:], c.ru)
+			buf.Write(rb[:n])
+		}
+		// styles don't continue to the next line
+		if !st.IsZero() {
+			st = Style{}
+			ops = append(ops, &Op{Offset: buf.Len()})
+		}
+	}
+	return buf.Bytes(), ops
+}
+
+//----------
+
+func (t *Term) setSGR(args []int) {
+	if len(args) == 0 {
+		args = []int{0}
+	}
+	s := &t.sgr
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case a == 0:
+			*s = sgrState{}
+		case a == 1:
+			s.bold = true
+		case a == 22:
+			s.bold = false
+		case a == 7:
+			s.reverse = true
+		case a == 27:
+			s.reverse = false
+		case a >= 30 && a <= 37:
+			s.fg = Palette[a-30]
+		case a == 39:
+			s.fg = nil
+		case a >= 40 && a <= 47:
+			s.bg = Palette[a-40]
+		case a == 49:
+			s.bg = nil
+		case a >= 90 && a <= 97:
+			s.fg = Palette[a-90+8]
+		case a >= 100 && a <= 107:
+			s.bg = Palette[a-100+8]
+		case a == 38 || a == 48:
+			c, n := extendedColor(args[i+1:])
+			i += n
+			if a == 38 {
+				s.fg = c
+			} else {
+				s.bg = c
+			}
+		}
+	}
+
+	// build style
+	fg, bg := s.fg, s.bg
+	if s.bold {
+		// bright version of the basic colors
+		for k := 0; k < 8; k++ {
+			if fg == Palette[k] {
+				fg = Palette[k+8]
+			}
+		}
+	}
+	if s.reverse {
+		fg, bg = bg, fg
+		if fg == nil {
+			fg = DefaultReverseFg
+		}
+		if bg == nil {
+			bg = DefaultReverseBg
+		}
+	}
+	t.style = Style{Fg: fg, Bg: bg}
+}
+
+// Returns the number of args used.
+func extendedColor(args []int) (color.Color, int) {
+	if len(args) == 0 {
+		return nil, 0
+	}
+	switch args[0] {
+	case 5: // 256 colors
+		if len(args) < 2 {
+			return nil, len(args)
+		}
+		return Color256(args[1]), 2
+	case 2: // rgb
+		if len(args) < 4 {
+			return nil, len(args)
+		}
+		c := color.RGBA{uint8(args[1]), uint8(args[2]), uint8(args[3]), 255}
+		return c, 4
+	}
+	return nil, 1
+}
+
+func parseParams(s string) []int {
+	if s == "" {
+		return nil
+	}
+	u := []int{}
+	for _, a := range bytes.Split([]byte(s), []byte(";")) {
+		v, _ := strconv.Atoi(string(a)) // empty is zero (default)
+		u = append(u, v)
+	}
+	return u
+}
+
+//----------
+
+type sgrState struct {
+	bold    bool
+	reverse bool
+	fg, bg  color.Color
+}
+
+type tline []tcell
+
+type tcell struct {
+	ru    rune
+	style Style
+}
+
+//----------
+
+type Style struct {
+	Fg, Bg color.Color
+}
+
+func (s Style) IsZero() bool {
+	return s.Fg == nil && s.Bg == nil
+}
+
+// Style starting at offset (zero style ends the previous style).
+type Op struct {
+	Offset int
+	Style  Style
+}
+
+//----------
+
+// Text changes to apply after a write.
+type Update struct {
+	Clear     bool   // clear all previous output (including commits)
+	Commit    []byte // text that left the screen (inserted before the screen)
+	CommitOps []*Op  // offsets relative to the commit start
+	Screen    []byte // replaces the previous screen text
+	ScreenOps []*Op  // offsets relative to the screen start
+}
+
+//----------
+
+var Palette = [16]color.Color{
+	// basic
+	cint(0x000000), // black
+	cint(0xcd0000), // red
+	cint(0x00a000), // green
+	cint(0xa08000), // yellow
+	cint(0x0000ee), // blue
+	cint(0xcd00cd), // magenta
+	cint(0x00a0a0), // cyan
+	cint(0xa0a0a0), // white
+	// bright
+	cint(0x7f7f7f),
+	cint(0xff0000),
+	cint(0x00d000),
+	cint(0xd0b000),
+	cint(0x5c5cff),
+	cint(0xff00ff),
+	cint(0x00d0d0),
+	cint(0xffffff),
+}
+
+var DefaultReverseFg color.Color = cint(0xffffff)
+var DefaultReverseBg color.Color = cint(0x000000)
+
+func Color256(i int) color.Color {
+	switch {
+	case i < 0 || i > 255:
+		return nil
+	case i < 16:
+		return Palette[i]
+	case i < 232: // 6x6x6 cube
+		i -= 16
+		v := func(k int) uint8 {
+			if k == 0 {
+				return 0
+			}
+			return uint8(55 + k*40)
+		}
+		return color.RGBA{v(i / 36), v((i / 6) % 6), v(i % 6), 255}
+	default: // grayscale
+		g := uint8(8 + (i-232)*10)
+		return color.RGBA{g, g, g, 255}
+	}
+}
+
+func cint(c int) color.RGBA {
+	return imageutil.IntRGBA(c)
+}
@@ -0,0 +1,82 @@
+package termutil
+
+import (
+	"testing"
+)
+
+func TestTerm1(t *testing.T) {
+	term := NewTerm(80, 24)
+	u := term.Write([]byte("abc\rd"))
+	testScreen(t, u, "dbc")
+
+	// progress bar
+	u = term.Write([]byte("\n10%\r20%\r"))
+	testScreen(t, u, "dbc\n20%")
+	u = term.Write([]byte("30%\n"))
+	testScreen(t, u, "dbc\n30%\n")
+}
+
+func TestTerm2(t *testing.T) {
+	term := NewTerm(80, 24)
+	// cursor up and erase line
+	u := term.Write([]byte("aaa\nbbb\n\x1b[2A\x1b[2Kccc\x1b[2B\r"))
+	testScreen(t, u, "ccc\nbbb\n")
+	// cursor back
+	u = term.Write([]byte("12345\x1b[3Dx"))
+	testScreen(t, u, "ccc\nbbb\n12x45")
+}
+
+func TestTerm3(t *testing.T) {
+	term := NewTerm(80, 2)
+	u := term.Write([]byte("a\nb\nc\nd"))
+	if string(u.Commit) != "a\nb\n" {
+		t.Fatalf("commit: %q", u.Commit)
+	}
+	testScreen(t, u, "c\nd")
+}
+
+func TestTerm4(t *testing.T) {
+	term := NewTerm(80, 24)
+	// colors (split escape sequence between writes)
+	u := term.Write([]byte("a\x1b[3"))
+	testScreen(t, u, "a")
+	u = term.Write([]byte("1mred\x1b[0mb"))
+	testScreen(t, u, "aredb")
+	if len(u.ScreenOps) != 2 {
+		t.Fatalf("ops: %v", len(u.ScreenOps))
+	}
+	op0, op1 := u.ScreenOps[0], u.ScreenOps[1]
+	if op0.Offset != 1 || op0.Style.Fg != Palette[1] {
+		t.Fatalf("op0: %+v", op0)
+	}
+	if op1.Offset != 4 || !op1.Style.IsZero() {
+		t.Fatalf("op1: %+v", op1)
+	}
+}
+
+func TestTerm5(t *testing.T) {
+	term := NewTerm(80, 24)
+	// clear screen, osc title
+	u := term.Write([]byte("abc\x1b]0;title\x07\x1b[H\x1b[2Jdef"))
+	if !u.Clear {
+		t.Fatal("expecting clear")
+	}
+	testScreen(t, u, "def")
+}
+
+func TestTerm6(t *testing.T) {
+	term := NewTerm(80, 24)
+	// utf8 split between writes
+	b := []byte("ação")
+	u := term.Write(b[:2])
+	testScreen(t, u, "a")
+	u = term.Write(b[2:])
+	testScreen(t, u, "ação")
+}
+
+func testScreen(t *testing.T, u *Update, s string) {
+	t.Helper()
+	if string(u.Screen) != s {
+		t.Fatalf("expecting %q, got %q", s, u.Screen)
+	}
+}
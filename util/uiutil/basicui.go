@@ -260,6 +260,16 @@ func (ui *BasicUI) RunOnUIGoRoutine(f func()) {
 	ui.AppendEvent(&UIRunFuncEvent{f})
 }
 
+// Waits for f to run. Should not be called from the UI goroutine (deadlock).
+func (ui *BasicUI) WaitRunOnUIGoRoutine(f func()) {
+	ch := make(chan struct{})
+	ui.RunOnUIGoRoutine(func() {
+		defer close(ch)
+		f()
+	})
+	<-ch
+}
+
 // Allows triggering a run of applyevent (ex: useful for cursor update).
 func (ui *BasicUI) QueueEmptyWindowInputEvent() {
 	p, err := ui.QueryPointer()
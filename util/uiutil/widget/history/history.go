@@ -93,3 +93,25 @@ func (h *History) ClearOldN(n int) {
 		n--
 	}
 }
+
+//----------
+
+// Shifts the edits indexes by n for content inserted at index outside of the history. Fails (nothing is changed) if an edit is before the index, since its undo/redo would not apply to the same content.
+func (h *History) ShiftIndexes(index, n int, shiftState func(interface{}) interface{}) bool {
+	for e := h.l.Front(); e != nil; e = e.Next() {
+		for _, ur := range e.Value.(*Edit).Entries() {
+			if ur.Index < index {
+				return false
+			}
+		}
+	}
+	for e := h.l.Front(); e != nil; e = e.Next() {
+		edit := e.Value.(*Edit)
+		for _, ur := range edit.Entries() {
+			ur.Index += n
+		}
+		edit.PreState = shiftState(edit.PreState)
+		edit.PostState = shiftState(edit.PostState)
+	}
+	return true
+}
@@ -0,0 +1,31 @@
+package history
+
+import (
+	"testing"
+
+	"github.com/jmigpin/editor/util/iout/iorw"
+)
+
+func TestShiftIndexes1(t *testing.T) {
+	h := NewHistory(10)
+	edit := &Edit{PreState: 5, PostState: 8}
+	edit.Append(&iorw.UndoRedo{Type: iorw.InsertWOp, Index: 5, B: []byte("abc")})
+	h.Append(edit)
+
+	shift := func(data interface{}) interface{} { return data.(int) + 2 }
+	if !h.ShiftIndexes(5, 2, shift) {
+		t.Fatal("expecting shift")
+	}
+	ur := edit.Entries()[0]
+	if ur.Index != 7 || edit.PreState != 7 || edit.PostState != 10 {
+		t.Fatal(ur.Index, edit.PreState, edit.PostState)
+	}
+
+	// edit before the index
+	if h.ShiftIndexes(8, 2, shift) {
+		t.Fatal("expecting no shift")
+	}
+	if ur.Index != 7 {
+		t.Fatal(ur.Index)
+	}
+}
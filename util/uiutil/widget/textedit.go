@@ -85,6 +85,37 @@ func (te *TextEdit) AppendBytesClearHistory(b []byte) error {
 	return nil
 }
 
+func (te *TextEdit) InsertBytesClearHistory(index int, b []byte) error {
+	rw := te.crw // bypass history
+	if err := rw.Insert(index, b); err != nil {
+		return err
+	}
+	te.TextHistory.clear()
+	te.contentChanged()
+	return nil
+}
+
+// Inserts outside of the history. The history is kept if all its edits are at or after the index (ex: text typed after the insertion point), otherwise it is cleared.
+func (te *TextEdit) InsertBytesKeepHistory(index int, b []byte) error {
+	rw := te.crw // bypass history
+	if err := rw.Insert(index, b); err != nil {
+		return err
+	}
+	te.TextHistory.shiftIndexes(index, len(b))
+	te.contentChanged()
+	return nil
+}
+
+func (te *TextEdit) OverwriteBytesClearHistory(index, n int, b []byte) error {
+	rw := te.crw // bypass history
+	if err := rw.Overwrite(index, n, b); err != nil {
+		return err
+	}
+	te.TextHistory.clear()
+	te.contentChanged()
+	return nil
+}
+
 //----------
 
 func (te *TextEdit) SetStr(str string) error {
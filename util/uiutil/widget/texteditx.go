@@ -41,11 +41,12 @@ func NewTextEditX(ctx ImageContext, cctx ClipboardContext) *TextEditX {
 
 		// setup colorize order
 		d.Opt.Colorize.Groups = []*drawer4.ColorizeGroup{
+			{}, // 0=custom
 			&d.Opt.SyntaxHighlight.Group,
 			&d.Opt.WordHighlight.Group,
 			&d.Opt.ParenthesisHighlight.Group,
-			{}, // 3=selection
-			{}, // 4=flash
+			{}, // 4=selection
+			{}, // 5=flash
 		}
 	}
 
@@ -69,7 +70,7 @@ func (te *TextEditX) Paint() {
 
 func (te *TextEditX) updateSelectionOpt() {
 	if d, ok := te.Drawer.(*drawer4.Drawer); ok {
-		g := d.Opt.Colorize.Groups[3]
+		g := d.Opt.Colorize.Groups[4]
 		if te.TextCursor.SelectionOn() {
 			// colors
 			pcol := te.TreeThemePaletteColor
@@ -95,6 +96,16 @@ func (te *TextEditX) updateSelectionOpt() {
 
 //----------
 
+// Ops must be ordered by offset. Drawn below the other colorizations (ex: terminal colors).
+func (te *TextEditX) SetCustomColorizeOps(ops []*drawer4.ColorizeOp) {
+	if d, ok := te.Drawer.(*drawer4.Drawer); ok {
+		d.Opt.Colorize.Groups[0].Ops = ops
+		te.MarkNeedsPaint()
+	}
+}
+
+//----------
+
 func (te *TextEditX) FlashLine(index int) {
 	te.startFlash(index, 0, true)
 }
@@ -170,7 +181,7 @@ func (te *TextEditX) updateFlashOpt() {
 }
 
 func (te *TextEditX) updateFlashOpt4(d *drawer4.Drawer) {
-	g := d.Opt.Colorize.Groups[4]
+	g := d.Opt.Colorize.Groups[5]
 	if !te.flash.index.on {
 		g.Ops = nil
 		return
@@ -29,6 +29,26 @@ func NewTextHistory(es *TextEdit) *TextHistory {
 func (th *TextHistory) clear() {
 	th.hist.Clear()
 }
+
+// Keeps the history (if possible) on content inserted at index outside of the history.
+func (th *TextHistory) shiftIndexes(index, n int) {
+	shift := func(data interface{}) interface{} {
+		state, ok := data.(TextCursorState)
+		if !ok {
+			return data
+		}
+		if state.index >= index {
+			state.index += n
+		}
+		if state.selectionIndex >= index {
+			state.selectionIndex += n
+		}
+		return state
+	}
+	if !th.hist.ShiftIndexes(index, n, shift) {
+		th.clear()
+	}
+}
 func (th *TextHistory) ClearForward() {
 	th.hist.ClearForward()
 }